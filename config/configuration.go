@@ -1,13 +1,19 @@
 package config
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
-	"gopkg.in/yaml.v2"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
 type ConfigFormat uint8
@@ -15,21 +21,126 @@ type ConfigFormat uint8
 const (
 	ConfigFormatJSON ConfigFormat = iota
 	ConfigFormatYAML
+	ConfigFormatTOML
+
+	// ConfigFormatAuto detects the format from the
+	// extension of the file or, if the extension isn't
+	// known, from its contents.
+	ConfigFormatAuto
 )
 
-func (format ConfigFormat) decode(src io.Reader, dst any) error {
+// StdinFile is the configuration file name
+// that reads the configuration from stdin.
+const StdinFile = "-"
+
+// detectFormat returns the format of the configuration
+// in data, read from srcFile, based on the extension of
+// srcFile or, if the extension isn't known, by sniffing
+// the contents.
+func detectFormat(srcFile string, data []byte) ConfigFormat {
+	switch filepath.Ext(srcFile) {
+	case ".json":
+		return ConfigFormatJSON
+
+	case ".yaml", ".yml":
+		return ConfigFormatYAML
+
+	case ".toml":
+		return ConfigFormatTOML
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return ConfigFormatJSON
+	}
+
+	// The first line that isn't blank or a comment is
+	// enough to tell TOML, which starts with a table
+	// header or a key = value pair, from YAML
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+
+		equals, colon := strings.Index(line, "="), strings.Index(line, ":")
+		if strings.HasPrefix(line, "[") || (equals >= 0 && (colon < 0 || equals < colon)) {
+			return ConfigFormatTOML
+		}
+
+		break
+	}
+
+	return ConfigFormatYAML
+}
+
+// decode decodes the configuration in src into dst, YAML
+// is decoded from root, the tree of nodes parsed from src
+// by parseNode, so that it is decoded exactly as checked.
+func (format ConfigFormat) decode(src io.Reader, root *yaml.Node, dst any) error {
 	switch format {
 	case ConfigFormatJSON:
 		return json.NewDecoder(src).Decode(dst)
 
 	case ConfigFormatYAML:
-		return yaml.NewDecoder(src).Decode(dst)
+		if root == nil {
+			return io.EOF
+		}
+
+		return root.Decode(dst)
+
+	case ConfigFormatTOML:
+		// The configuration types only have JSON and YAML
+		// tags, so TOML is decoded through JSON, which
+		// the types of TOML values map on to
+		var values map[string]any
+		if _, err := toml.NewDecoder(src).Decode(&values); err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(tomlLocalDates(values))
+		if err != nil {
+			return err
+		}
+
+		return json.Unmarshal(data, dst)
 
 	default:
 		return errors.New("unsupported config format")
 	}
 }
 
+// tomlLocalDates replaces each TOML local date in value with
+// its YYYY-MM-DD form, so that a date such as an expiry is
+// decoded the same as the date written as a string rather
+// than as midnight of that day.
+func tomlLocalDates(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, item := range value {
+			value[key] = tomlLocalDates(item)
+		}
+
+	case []map[string]any:
+		for _, item := range value {
+			tomlLocalDates(item)
+		}
+
+	case []any:
+		for i, item := range value {
+			value[i] = tomlLocalDates(item)
+		}
+
+	case time.Time:
+		// The decoder marks local dates with a
+		// zone of its own that isn't exported
+		if value.Location().String() == "date-local" {
+			return value.Format(time.DateOnly)
+		}
+	}
+
+	return value
+}
+
 var ErrUnsupportedVersion = errors.New("unsupported configuration version")
 
 type configuration interface {
@@ -37,9 +148,10 @@ type configuration interface {
 }
 
 type Package struct {
-	File     string `json:"file" yaml:"file"`
-	BundleID string `json:"bundle_id" yaml:"bundle_id"`
-	Staple   bool   `json:"staple" yaml:"staple"`
+	File     string   `json:"file" yaml:"file"`
+	BundleID string   `json:"bundle_id" yaml:"bundle_id"`
+	Staple   bool     `json:"staple" yaml:"staple"`
+	Exclude  []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
 }
 
 type configVersion struct {
@@ -54,40 +166,165 @@ func (ver *configVersion) getTargetType() (configuration, error) {
 	case 2:
 		return new(ConfigurationV2), nil
 
+	case 3:
+		return new(ConfigurationV3), nil
+
 	default:
 		return nil, ErrUnsupportedVersion
 	}
 }
 
-func LoadConfigurationFromFile(srcFile string, format ConfigFormat) (*ConfigurationV2, error) {
-	src, err := os.OpenFile(srcFile, os.O_RDONLY, 0644)
+// LoadConfigurationFromFile loads the configuration
+// from srcFile, upgrading older versions to V3, and
+// resolves any secret references in it.
+func LoadConfigurationFromFile(srcFile string, format ConfigFormat) (*ConfigurationV3, error) {
+	return LoadConfiguration(srcFile, format, Overrides{})
+}
+
+// LoadConfiguration loads the configuration from srcFile,
+// as LoadConfigurationFromFile does, with the overrides
+// applied. If srcFile is empty the configuration is made
+// up of the overrides alone.
+func LoadConfiguration(srcFile string, format ConfigFormat, overrides Overrides) (*ConfigurationV3, error) {
+	v3, err := ParseConfiguration(srcFile, format, overrides)
 	if err != nil {
-		return nil, fmt.Errorf("open configuration file: %w", err)
+		return nil, err
 	}
-	defer src.Close()
 
-	var configVer configVersion
-	if err = format.decode(src, &configVer); err != nil {
-		return nil, fmt.Errorf("decode config version: %w", err)
-	} else if _, err = src.Seek(0, io.SeekStart); err != nil {
-		return nil, fmt.Errorf("seek to start of config: %w", err)
+	return v3, v3.ResolveSecrets(context.Background())
+}
+
+// ParseConfiguration loads the configuration from srcFile,
+// upgrading older versions to V3, applies the overrides, and
+// validates it but, unlike LoadConfiguration, leaves any
+// secret references unresolved until ResolveSecrets is
+// called. If srcFile is empty the configuration is made
+// up of the overrides alone.
+func ParseConfiguration(srcFile string, format ConfigFormat, overrides Overrides) (*ConfigurationV3, error) {
+	v3, err := parseConfigurationFile(srcFile, format)
+	if err != nil {
+		return nil, err
 	}
 
-	config, err := configVer.getTargetType()
+	overrides.apply(v3)
+
+	if len(v3.Packages) == 0 {
+		return nil, errors.New("no packages specified")
+	} else if err = v3.expandPackages(); err != nil {
+		return nil, err
+	} else if err = v3.validate(); err != nil {
+		return nil, err
+	}
+
+	if srcFile == StdinFile {
+		for i := range v3.Packages {
+			if auth := v3.Settings(&v3.Packages[i]).NotaryAuth; auth.KeyFile == StdinFile {
+				return nil, fmt.Errorf("package '%s': key_file can't be read from stdin as the configuration is read from stdin", v3.Packages[i].File)
+			}
+		}
+	}
+
+	return v3, nil
+}
+
+// parseConfigurationFile decodes srcFile and
+// upgrades it to V3, an empty srcFile results
+// in an empty configuration.
+func parseConfigurationFile(srcFile string, format ConfigFormat) (*ConfigurationV3, error) {
+	if len(srcFile) == 0 {
+		return new(ConfigurationV3), nil
+	}
+
+	config, err := decodeConfigurationFile(srcFile, format)
 	if err != nil {
 		return nil, err
-	} else if err = format.decode(src, config); err != nil {
-		return nil, fmt.Errorf("decode configuration file: %w", err)
 	}
 
+	var v3 *ConfigurationV3
 	switch t := config.(type) {
 	case *ConfigurationV1:
-		return t.ToV2()
+		v3, err = t.ToV3()
 
 	case *ConfigurationV2:
-		return t, nil
+		v3, err = t.ToV3()
+
+	case *ConfigurationV3:
+		v3 = t
 
 	default:
 		return nil, ErrUnsupportedVersion
 	}
+
+	return v3, err
+}
+
+// LoadConfigurationV1FromFile loads a version 1
+// configuration from srcFile as is, so that it
+// can be migrated to a newer version.
+func LoadConfigurationV1FromFile(srcFile string, format ConfigFormat) (*ConfigurationV1, error) {
+	config, err := decodeConfigurationFile(srcFile, format)
+	if err != nil {
+		return nil, err
+	}
+
+	v1, ok := config.(*ConfigurationV1)
+	if !ok {
+		return nil, fmt.Errorf("%w: configuration is already a newer version than 1", ErrUnsupportedVersion)
+	}
+
+	return v1, nil
+}
+
+// readConfigurationFile reads the contents of srcFile,
+// or of stdin if srcFile is StdinFile.
+func readConfigurationFile(srcFile string) ([]byte, error) {
+	if srcFile == StdinFile {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("read configuration from stdin: %w", err)
+		}
+
+		return data, nil
+	}
+
+	data, err := os.ReadFile(srcFile)
+	if err != nil {
+		return nil, fmt.Errorf("open configuration file: %w", err)
+	}
+
+	return data, nil
+}
+
+// decodeConfigurationFile decodes srcFile into
+// the type for its configuration version.
+func decodeConfigurationFile(srcFile string, format ConfigFormat) (configuration, error) {
+	data, err := readConfigurationFile(srcFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == ConfigFormatAuto {
+		format = detectFormat(srcFile, data)
+	}
+
+	root, err := format.parseNode(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse configuration file: %w", err)
+	}
+
+	var configVer configVersion
+	if err = format.decode(bytes.NewReader(data), root, &configVer); err != nil {
+		return nil, fmt.Errorf("decode config version: %w", err)
+	}
+
+	config, err := configVer.getTargetType()
+	if err != nil {
+		return nil, err
+	} else if err = format.check(root, config); err != nil {
+		return nil, err
+	} else if err = format.decode(bytes.NewReader(data), root, config); err != nil {
+		return nil, fmt.Errorf("decode configuration file: %w", err)
+	}
+
+	return config, nil
 }
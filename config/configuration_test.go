@@ -0,0 +1,221 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		file, data string
+		expected   ConfigFormat
+	}{
+		{"config.json", "config_version = 3\n", ConfigFormatJSON},
+		{"config.yaml", "config_version = 3\n", ConfigFormatYAML},
+		{"config.yml", "{\"config_version\": 3}\n", ConfigFormatYAML},
+		{"config.toml", "config_version: 3\n", ConfigFormatTOML},
+
+		// Without a known extension the
+		// contents are sniffed
+		{"config", "{\"config_version\": 3}\n", ConfigFormatJSON},
+		{"config.conf", "\n  {\n  \"config_version\": 3\n}\n", ConfigFormatJSON},
+		{"config.conf", "config_version: 3\n", ConfigFormatYAML},
+		{"config.conf", "---\nconfig_version: 3\n", ConfigFormatYAML},
+		{"config.conf", "# key = value\nconfig_version: 3\n", ConfigFormatYAML},
+		{"config.conf", "key_file: \"a=b\"\n", ConfigFormatYAML},
+		{"config.conf", "- file: App.dmg\n", ConfigFormatYAML},
+		{"config.conf", "config_version = 3\n", ConfigFormatTOML},
+		{"config.conf", "url = \"http://x\"\n", ConfigFormatTOML},
+		{"config.conf", "# key: value\n\n[defaults]\n", ConfigFormatTOML},
+		{"-", "config_version = 3\n", ConfigFormatTOML},
+		{"config.conf", "", ConfigFormatYAML},
+	}
+
+	for _, test := range tests {
+		if format := detectFormat(test.file, []byte(test.data)); format != test.expected {
+			t.Errorf("detectFormat(%q, %q) = %d, expected %d", test.file, test.data, format, test.expected)
+		}
+	}
+}
+
+func TestDecodeTOMLStrict(t *testing.T) {
+	tests := []struct {
+		name, data, expected string
+	}{
+		{
+			name: "unknown field",
+			data: "config_version = 3\n" +
+				"[defaults]\n" +
+				"stapel = true\n" +
+				"[[packages]]\n" +
+				"file = \"App.dmg\"\n",
+			expected: "defaults: unknown field 'stapel', did you mean 'staple'?",
+		},
+		{
+			name: "type mismatch",
+			data: "config_version = 3\n" +
+				"[upload]\n" +
+				"part_size = \"big\"\n" +
+				"[[packages]]\n" +
+				"file = \"App.dmg\"\n" +
+				"staple = \"yes\"\n",
+			expected: "packages[0].staple: expected a boolean but found a string\n" +
+				"upload.part_size: expected an integer but found a string",
+		},
+		{
+			name: "table for a list",
+			data: "config_version = 3\n" +
+				"[packages]\n" +
+				"file = \"App.dmg\"\n",
+			expected: "packages: expected a list but found a table",
+		},
+		{
+			name: "syntax error",
+			data: "config_version = 3\n" +
+				"[[packages]]\n" +
+				"file = \"App.dmg\"\n" +
+				"staple = yes\n",
+			expected: "parse configuration file: line 4, column 10: expected value but found \"yes\" instead",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := decodeString(t, "config.toml", test.data); err == nil {
+				t.Error("expected an error")
+			} else if err.Error() != test.expected {
+				t.Errorf("expected error:\n%s\ngot:\n%v", test.expected, err)
+			}
+		})
+	}
+}
+
+// decodePolicy decodes data, written to a file named
+// name, and resolves the policy of its defaults.
+func decodePolicy(t *testing.T, name, data string) []time.Time {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+
+	config, err := decodeConfigurationFile(path, ConfigFormatAuto)
+	if err != nil {
+		t.Fatalf("decode %s: %v", name, err)
+	}
+
+	resolved, err := config.(*ConfigurationV3).Defaults.Policy.Resolve()
+	if err != nil {
+		t.Fatalf("resolve policy of %s: %v", name, err)
+	}
+
+	expires := make([]time.Time, 0, len(resolved.Allow))
+	for _, allow := range resolved.Allow {
+		expires = append(expires, allow.Expires)
+	}
+
+	return expires
+}
+
+func TestDecodeTOMLDates(t *testing.T) {
+	toml := decodePolicy(t, "config.toml", "config_version = 3\n"+
+		"[[defaults.policy.allow]]\n"+
+		"code = \"a\"\n"+
+		"expires = 2026-06-30\n"+
+		"[[defaults.policy.allow]]\n"+
+		"code = \"b\"\n"+
+		"expires = 2026-06-30T12:00:00Z\n"+
+		"[[defaults.policy.allow]]\n"+
+		"code = \"c\"\n"+
+		"expires = \"2026-06-30\"\n"+
+		"[[packages]]\n"+
+		"file = \"App.dmg\"\n")
+
+	yaml := decodePolicy(t, "config.yaml", "config_version: 3\n"+
+		"defaults:\n"+
+		"  policy:\n"+
+		"    allow:\n"+
+		"      - code: a\n"+
+		"        expires: 2026-06-30\n"+
+		"      - code: b\n"+
+		"        expires: 2026-06-30T12:00:00Z\n"+
+		"      - code: c\n"+
+		"        expires: \"2026-06-30\"\n"+
+		"packages:\n"+
+		"  - file: App.dmg\n")
+
+	expected := []time.Time{
+		time.Date(2026, 6, 30, 23, 59, 59, 999999999, time.UTC),
+		time.Date(2026, 6, 30, 12, 0, 0, 0, time.UTC),
+		time.Date(2026, 6, 30, 23, 59, 59, 999999999, time.UTC),
+	}
+
+	for i := range expected {
+		if !toml[i].Equal(expected[i]) {
+			t.Errorf("TOML allowance %d expires %s, expected %s", i, toml[i], expected[i])
+		}
+
+		if !yaml[i].Equal(expected[i]) {
+			t.Errorf("YAML allowance %d expires %s, expected %s", i, yaml[i], expected[i])
+		}
+	}
+}
+
+// withStdin replaces os.Stdin, for the rest
+// of the test, with a file containing data.
+func withStdin(t *testing.T, data string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("write stdin: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open stdin: %v", err)
+	}
+
+	stdin := os.Stdin
+	os.Stdin = file
+
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		_ = file.Close()
+	})
+}
+
+func TestParseConfigurationStdin(t *testing.T) {
+	tests := []struct {
+		keyFile string
+		valid   bool
+	}{
+		{"AuthKey.p8", true},
+		{"-", false},
+	}
+
+	for _, test := range tests {
+		withStdin(t, "config_version: 3\n"+
+			"packages:\n"+
+			"  - file: App.dmg\n"+
+			"    notary_auth:\n"+
+			"      key_id: ABC123\n"+
+			"      key_file: \""+test.keyFile+"\"\n")
+
+		_, err := ParseConfiguration(StdinFile, ConfigFormatAuto, Overrides{})
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("parse configuration with key_file '%s' returned error: %v", test.keyFile, err)
+
+		case !test.valid && err == nil:
+			t.Errorf("parse configuration with key_file '%s' expected an error", test.keyFile)
+
+		case !test.valid && !strings.Contains(err.Error(), "key_file can't be read from stdin"):
+			t.Errorf("parse configuration with key_file '%s' returned unexpected error: %v", test.keyFile, err)
+		}
+	}
+}
@@ -1,11 +1,5 @@
 package config
 
-import (
-	"fmt"
-	"os"
-	"strings"
-)
-
 type ConfigurationV1 struct {
 	Username string    `json:"username" yaml:"username"`
 	Password string    `json:"password" yaml:"password"`
@@ -17,25 +11,26 @@ func (config *ConfigurationV1) GetPackages() []Package {
 	return config.Packages
 }
 
+// ToV2 converts the configuration to the V2 schema, secret
+// references in the credentials are carried over as is and
+// only resolved when the credentials are first used.
 func (config *ConfigurationV1) ToV2() (*ConfigurationV2, error) {
-	password := config.Password
-
-	if envKey, found := strings.CutPrefix(password, "ENV:"); found {
-		password = os.Getenv(envKey)
-	} else if keychainLabel, found := strings.CutPrefix(password, "@keychain:"); found {
-		var err error
-
-		if password, err = getPasswordFromKeychain(keychainLabel); err != nil {
-			return nil, fmt.Errorf("get password from keychain: %w", err)
-		}
-	}
-
 	return &ConfigurationV2{
 		NotaryAuth: &ConfigurationV2_NotaryAuth{
-			username:            config.Username,
-			appSpecificPassword: password,
-			teamId:              config.TeamID,
+			Username: config.Username,
+			Password: config.Password,
+			TeamId:   config.TeamID,
 		},
 		Packages: config.Packages,
 	}, nil
 }
+
+// ToV3 converts the configuration to the V3 schema.
+func (config *ConfigurationV1) ToV3() (*ConfigurationV3, error) {
+	v2, err := config.ToV2()
+	if err != nil {
+		return nil, err
+	}
+
+	return v2.ToV3()
+}
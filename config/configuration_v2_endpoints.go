@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+)
+
+const (
+	envNotaryApiUrl       = "NOTARY_API_URL"
+	envNotaryTicketUrl    = "NOTARY_TICKET_LOOKUP_URL"
+	envNotaryS3Region     = "NOTARY_S3_REGION"
+	envNotaryS3Endpoint   = "NOTARY_S3_ENDPOINT"
+	envNotaryS3Accelerate = "NOTARY_S3_ACCELERATE"
+)
+
+// ConfigurationV2_Endpoints overrides the location
+// of the services used for notarization, any field
+// left empty uses the service operated by Apple.
+//
+// Each field can also be overridden by an environment
+// variable, which takes precedence over the value in
+// the configuration file.
+type ConfigurationV2_Endpoints struct {
+	NotaryAPI    string `json:"notary_api" yaml:"notary_api"`
+	TicketLookup string `json:"ticket_lookup" yaml:"ticket_lookup"`
+	S3Region     string `json:"s3_region" yaml:"s3_region"`
+	S3Endpoint   string `json:"s3_endpoint" yaml:"s3_endpoint"`
+	S3Accelerate *bool  `json:"s3_accelerate" yaml:"s3_accelerate"`
+}
+
+// Resolve merges the endpoints from the configuration
+// and environment over the default endpoints, it is
+// safe to call on a nil ConfigurationV2_Endpoints.
+func (endpoints *ConfigurationV2_Endpoints) Resolve() (api.Endpoints, error) {
+	resolved := api.DefaultEndpoints()
+
+	if endpoints != nil {
+		overrideString(&resolved.NotaryAPI, endpoints.NotaryAPI)
+		overrideString(&resolved.TicketLookup, endpoints.TicketLookup)
+		overrideString(&resolved.S3Region, endpoints.S3Region)
+		overrideString(&resolved.S3Endpoint, endpoints.S3Endpoint)
+
+		if endpoints.S3Accelerate != nil {
+			resolved.S3Accelerate = *endpoints.S3Accelerate
+		}
+	}
+
+	overrideString(&resolved.NotaryAPI, os.Getenv(envNotaryApiUrl))
+	overrideString(&resolved.TicketLookup, os.Getenv(envNotaryTicketUrl))
+	overrideString(&resolved.S3Region, os.Getenv(envNotaryS3Region))
+	overrideString(&resolved.S3Endpoint, os.Getenv(envNotaryS3Endpoint))
+
+	if accelerate := os.Getenv(envNotaryS3Accelerate); len(accelerate) > 0 {
+		var err error
+		if resolved.S3Accelerate, err = strconv.ParseBool(accelerate); err != nil {
+			return resolved, fmt.Errorf("parse %s: %w", envNotaryS3Accelerate, err)
+		}
+	}
+
+	return resolved, nil
+}
+
+func overrideString(dst *string, value string) {
+	if len(value) > 0 {
+		*dst = value
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+)
+
+// ConfigurationV2_Network specifies the network settings
+// applied to every connection made during notarization,
+// to the Notary API, S3, and CloudKit alike.
+type ConfigurationV2_Network struct {
+	// Proxy is the URL of the HTTP proxy to send
+	// requests through, credentials for an
+	// authenticating proxy can be included in the
+	// URL. If empty the HTTPS_PROXY and HTTP_PROXY
+	// environment variables are used.
+	Proxy string `json:"proxy" yaml:"proxy"`
+
+	// NoProxy is a comma separated list of hosts
+	// that requests are sent to directly, if empty
+	// the NO_PROXY environment variable is used.
+	NoProxy string `json:"no_proxy" yaml:"no_proxy"`
+
+	// CABundle is the path to a PEM file of
+	// certificate authorities that are trusted
+	// in addition to the system roots, such as
+	// that of a TLS-intercepting proxy.
+	CABundle string `json:"ca_bundle" yaml:"ca_bundle"`
+}
+
+// ClientOptions returns the API client options that
+// apply these network settings, it is safe to call on
+// a nil ConfigurationV2_Network.
+func (network *ConfigurationV2_Network) ClientOptions() ([]api.ClientOption, error) {
+	if network == nil {
+		return nil, nil
+	}
+
+	// Either setting, when not configured, is taken from
+	// the environment by the client, so that no_proxy also
+	// applies to a proxy from HTTPS_PROXY or HTTP_PROXY
+	var opts []api.ClientOption
+	if len(network.Proxy) > 0 {
+		proxy, err := url.Parse(network.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy URL: %w", err)
+		}
+
+		opts = append(opts, api.WithProxy(proxy))
+	}
+
+	if len(network.NoProxy) > 0 {
+		opts = append(opts, api.WithNoProxy(network.NoProxy))
+	}
+
+	if len(network.CABundle) > 0 {
+		pool, err := loadCABundle(network.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("load CA bundle: %w", err)
+		}
+
+		opts = append(opts, api.WithRootCAs(pool))
+	}
+
+	return opts, nil
+}
+
+// HTTPClient returns an http.Client that applies these
+// network settings, for services other than the Notary
+// API such as secret stores, it is safe to call on a nil
+// ConfigurationV2_Network.
+func (network *ConfigurationV2_Network) HTTPClient() (*http.Client, error) {
+	opts, err := network.ClientOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := api.NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.HTTPClient(), nil
+}
+
+func loadCABundle(path string) (*x509.CertPool, error) {
+	bundle, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, errors.New("no PEM encoded certificates found in CA bundle")
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+)
+
+// proxyFor returns the proxy the client created with the
+// network settings sends a request for rawUrl through.
+func proxyFor(t *testing.T, network *ConfigurationV2_Network, rawUrl string) string {
+	t.Helper()
+
+	opts, err := network.ClientOptions()
+	if err != nil {
+		t.Fatalf("ClientOptions() returned error: %v", err)
+	}
+
+	client, err := api.NewClient(opts...)
+	if err != nil {
+		t.Fatalf("create API client: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawUrl, nil)
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+
+	proxy, err := client.HTTPClient().Transport.(*http.Transport).Proxy(req)
+	if err != nil {
+		t.Fatalf("get proxy for '%s': %v", rawUrl, err)
+	} else if proxy == nil {
+		return ""
+	}
+
+	return proxy.String()
+}
+
+func TestNetworkProxy(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "http://env-proxy.example:3128")
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("NO_PROXY", "env-direct.example")
+
+	tests := []struct {
+		name     string
+		network  *ConfigurationV2_Network
+		url      string
+		expected string
+	}{
+		{"environment proxy", nil, "https://s3.example", "http://env-proxy.example:3128"},
+		{"environment no_proxy", nil, "https://env-direct.example", ""},
+		{"configured no_proxy with environment proxy", &ConfigurationV2_Network{NoProxy: ".apple.com"}, "https://appstoreconnect.apple.com", ""},
+		{"configured no_proxy replaces environment", &ConfigurationV2_Network{NoProxy: ".apple.com"}, "https://env-direct.example", "http://env-proxy.example:3128"},
+		{"configured proxy", &ConfigurationV2_Network{Proxy: "http://proxy.corp:8080"}, "https://s3.example", "http://proxy.corp:8080"},
+		{"configured proxy with environment no_proxy", &ConfigurationV2_Network{Proxy: "http://proxy.corp:8080"}, "https://env-direct.example", ""},
+		{"configured proxy and no_proxy", &ConfigurationV2_Network{Proxy: "http://proxy.corp:8080", NoProxy: ".apple.com"}, "https://appstoreconnect.apple.com", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if proxy := proxyFor(t, test.network, test.url); proxy != test.expected {
+				t.Errorf("proxy for '%s' = %q, expected %q", test.url, proxy, test.expected)
+			}
+		})
+	}
+}
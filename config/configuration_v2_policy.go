@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/internal/glob"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/policy"
+)
+
+// ConfigurationV2_Policy specifies which issues in the
+// notarization log fail a package, even if the Notary
+// accepted it.
+type ConfigurationV2_Policy struct {
+	FailOnSeverity []string                      `json:"fail_on_severity" yaml:"fail_on_severity"`
+	FailOnCodes    []string                      `json:"fail_on_codes" yaml:"fail_on_codes"`
+	FailOnPaths    []string                      `json:"fail_on_paths" yaml:"fail_on_paths"`
+	Allow          []ConfigurationV2_PolicyAllow `json:"allow" yaml:"allow"`
+}
+
+// ConfigurationV2_PolicyAllow allows a known issue,
+// optionally until the date specified by Expires
+// (either YYYY-MM-DD or RFC 3339).
+type ConfigurationV2_PolicyAllow struct {
+	Code    string `json:"code" yaml:"code"`
+	Path    string `json:"path" yaml:"path"`
+	Message string `json:"message" yaml:"message"`
+	Expires string `json:"expires" yaml:"expires"`
+	Reason  string `json:"reason" yaml:"reason"`
+}
+
+// Resolve validates the policy configuration and
+// returns the policy to evaluate, it is safe to call
+// on a nil ConfigurationV2_Policy which returns a nil
+// policy.
+func (cfg *ConfigurationV2_Policy) Resolve() (*policy.Policy, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	resolved := &policy.Policy{
+		FailOnSeverity: cfg.FailOnSeverity,
+		FailOnCodes:    cfg.FailOnCodes,
+		FailOnPaths:    cfg.FailOnPaths,
+	}
+
+	for _, pattern := range cfg.FailOnPaths {
+		if err := glob.Validate(pattern); err != nil {
+			return nil, fmt.Errorf("invalid fail_on_paths glob '%s': %w", pattern, err)
+		}
+	}
+
+	for i, allow := range cfg.Allow {
+		if len(allow.Code) == 0 && len(allow.Path) == 0 && len(allow.Message) == 0 {
+			return nil, fmt.Errorf("allow entry %d must specify at least one of code, path, or message", i)
+		}
+
+		if err := glob.Validate(allow.Path); err != nil {
+			return nil, fmt.Errorf("invalid path glob '%s' in allow entry %d: %w", allow.Path, i, err)
+		}
+
+		expires, err := parseExpiry(allow.Expires)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiry in allow entry %d: %w", i, err)
+		}
+
+		resolved.Allow = append(resolved.Allow, policy.Allowance{
+			Code:    allow.Code,
+			Path:    allow.Path,
+			Message: allow.Message,
+			Expires: expires,
+			Reason:  allow.Reason,
+		})
+	}
+
+	return resolved, nil
+}
+
+// parseExpiry parses an expiry date, a date without
+// a time expires at the end of that day (UTC).
+func parseExpiry(value string) (time.Time, error) {
+	if len(value) == 0 {
+		return time.Time{}, nil
+	}
+
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		return date.Add(24*time.Hour - time.Nanosecond), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
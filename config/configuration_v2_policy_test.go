@@ -0,0 +1,116 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseExpiry(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Time
+		valid    bool
+	}{
+		{"", time.Time{}, true},
+		{"2026-06-30", time.Date(2026, 6, 30, 23, 59, 59, 999999999, time.UTC), true},
+		{"2026-06-30T12:00:00Z", time.Date(2026, 6, 30, 12, 0, 0, 0, time.UTC), true},
+		{"2026-06-30T12:00:00+10:00", time.Date(2026, 6, 30, 2, 0, 0, 0, time.UTC), true},
+		{"2026-06-30T12:00:00", time.Time{}, false},
+		{"30/06/2026", time.Time{}, false},
+		{"2026-02-30", time.Time{}, false},
+	}
+
+	for _, test := range tests {
+		expires, err := parseExpiry(test.value)
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("parseExpiry(%q) returned error: %v", test.value, err)
+
+		case !test.valid && err == nil:
+			t.Errorf("parseExpiry(%q) expected an error", test.value)
+
+		case test.valid && !expires.Equal(test.expected):
+			t.Errorf("parseExpiry(%q) = %s, expected %s", test.value, expires, test.expected)
+		}
+	}
+}
+
+func TestPolicyResolve(t *testing.T) {
+	var unset *ConfigurationV2_Policy
+	if resolved, err := unset.Resolve(); err != nil || resolved != nil {
+		t.Errorf("Resolve() of a nil policy = %v, %v, expected nil", resolved, err)
+	}
+
+	cfg := &ConfigurationV2_Policy{
+		FailOnSeverity: []string{"error"},
+		FailOnCodes:    []string{"-1"},
+		FailOnPaths:    []string{"**/PlugIns/**"},
+		Allow: []ConfigurationV2_PolicyAllow{
+			{Code: "-2", Expires: "2026-06-30", Reason: "Legacy framework is replaced in 2.0"},
+			{Path: "Example.app/**", Message: "hardened runtime"},
+		},
+	}
+
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	}
+
+	if len(resolved.FailOnSeverity) != 1 || len(resolved.FailOnCodes) != 1 || len(resolved.FailOnPaths) != 1 {
+		t.Errorf("fail rules weren't resolved: %+v", resolved)
+	}
+
+	if len(resolved.Allow) != 2 {
+		t.Fatalf("expected 2 allowances, got %d", len(resolved.Allow))
+	}
+
+	allow := resolved.Allow[0]
+	if expected := time.Date(2026, 6, 30, 23, 59, 59, 999999999, time.UTC); allow.Code != "-2" || !allow.Expires.Equal(expected) || allow.Reason != cfg.Allow[0].Reason {
+		t.Errorf("allowance resolved as %+v", allow)
+	}
+
+	if allow = resolved.Allow[1]; allow.Path != "Example.app/**" || allow.Message != "hardened runtime" || !allow.Expires.IsZero() {
+		t.Errorf("allowance resolved as %+v", allow)
+	}
+}
+
+func TestPolicyResolveErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *ConfigurationV2_Policy
+		expected string
+	}{
+		{
+			name:     "invalid fail_on_paths glob",
+			cfg:      &ConfigurationV2_Policy{FailOnPaths: []string{"**/[PlugIns/**"}},
+			expected: "invalid fail_on_paths glob '**/[PlugIns/**': syntax error in pattern",
+		},
+		{
+			name:     "empty allowance",
+			cfg:      &ConfigurationV2_Policy{Allow: []ConfigurationV2_PolicyAllow{{Code: "-1"}, {Reason: "everything"}}},
+			expected: "allow entry 1 must specify at least one of code, path, or message",
+		},
+		{
+			name:     "invalid allowance path glob",
+			cfg:      &ConfigurationV2_Policy{Allow: []ConfigurationV2_PolicyAllow{{Path: "Example.app/["}}},
+			expected: "invalid path glob 'Example.app/[' in allow entry 0: syntax error in pattern",
+		},
+		{
+			name:     "invalid expiry",
+			cfg:      &ConfigurationV2_Policy{Allow: []ConfigurationV2_PolicyAllow{{Code: "-1", Expires: "next week"}}},
+			expected: "invalid expiry in allow entry 0: ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.cfg.Resolve()
+			if err == nil {
+				t.Fatal("expected an error")
+			} else if !strings.HasPrefix(err.Error(), test.expected) {
+				t.Errorf("Resolve() returned error %q, expected %q", err, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newDERKeyEndingInNewline returns a PKCS #8 ES256 key, and
+// its DER encoding, whose last byte is a newline, so that
+// trimming the key as text would corrupt it.
+func newDERKeyEndingInNewline(t *testing.T) (*ecdsa.PrivateKey, []byte) {
+	t.Helper()
+
+	for {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("generate key: %v", err)
+		}
+
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("marshal key: %v", err)
+		}
+
+		if der[len(der)-1] == '\n' {
+			return key, der
+		}
+	}
+}
+
+func TestLoadAppStoreConnectKey(t *testing.T) {
+	key, der := newDERKeyEndingInNewline(t)
+	dir := t.TempDir()
+
+	writeKey := func(name string, contents []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, contents, 0600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+
+		return path
+	}
+
+	derFile := writeKey("AuthKey.der", der)
+	pemFile := writeKey("AuthKey.p8", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	base64File := writeKey("AuthKey.b64", []byte(base64.StdEncoding.EncodeToString(der)+"\n"))
+
+	t.Setenv("TEST_NOTARY_KEY", base64.StdEncoding.EncodeToString(der))
+
+	tests := []struct {
+		keyFile string
+		valid   bool
+	}{
+		{derFile, true},
+		{pemFile, true},
+		{"file:" + pemFile, true},
+		{"file:" + base64File, true},
+		{"env:TEST_NOTARY_KEY", true},
+
+		// Resolving a file: reference trims the
+		// trailing newline of the DER encoded key
+		{"file:" + derFile, false},
+	}
+
+	for _, test := range tests {
+		auth := &ConfigurationV2_NotaryAuth{KeyId: "ABC123", KeyFile: test.keyFile}
+		if err := auth.resolveSecrets(context.Background()); err != nil {
+			t.Fatalf("resolve secrets of '%s': %v", test.keyFile, err)
+		}
+
+		loaded, err := auth.loadAppStoreConnectKey()
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("load key from '%s' returned error: %v", test.keyFile, err)
+
+		case test.valid && !loaded.Equal(key):
+			t.Errorf("key loaded from '%s' doesn't match", test.keyFile)
+
+		case !test.valid && err == nil:
+			t.Errorf("load key from '%s' expected an error", test.keyFile)
+		}
+	}
+}
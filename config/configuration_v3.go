@@ -0,0 +1,302 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/config/secret"
+	"github.com/KatelynHaworth/notarization-helper/v2/internal/glob"
+)
+
+// ConfigurationV3 allows each package to override the
+// settings used to notarize it, any setting a package
+// doesn't specify is taken from Defaults.
+type ConfigurationV3 struct {
+	// NotaryAuthProfiles are named credentials that
+	// packages, or the defaults, can select using
+	// auth_profile. Packages using the same profile
+	// share its auth token.
+	NotaryAuthProfiles map[string]*ConfigurationV2_NotaryAuth `json:"notary_auth_profiles,omitempty" yaml:"notary_auth_profiles,omitempty"`
+
+	Upload    *ConfigurationV2_Upload    `json:"upload,omitempty" yaml:"upload,omitempty"`
+	Endpoints *ConfigurationV2_Endpoints `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+	Network   *ConfigurationV2_Network   `json:"network,omitempty" yaml:"network,omitempty"`
+	Defaults  ConfigurationV3_Settings   `json:"defaults" yaml:"defaults"`
+	Packages  []ConfigurationV3_Package  `json:"packages" yaml:"packages"`
+}
+
+// ConfigurationV3_Settings are the settings that
+// can be specified for each package, each setting
+// left unset is inherited from the defaults.
+type ConfigurationV3_Settings struct {
+	NotaryAuth *ConfigurationV2_NotaryAuth `json:"notary_auth,omitempty" yaml:"notary_auth,omitempty"`
+
+	// AuthProfile selects one of the named profiles
+	// in NotaryAuthProfiles instead of specifying
+	// NotaryAuth inline.
+	AuthProfile string `json:"auth_profile,omitempty" yaml:"auth_profile,omitempty"`
+
+	// Timeout is the maximum time allowed for the
+	// package to be notarized, such as "30m".
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	Staple  *bool                    `json:"staple,omitempty" yaml:"staple,omitempty"`
+	Archive *ConfigurationV2_Archive `json:"archive,omitempty" yaml:"archive,omitempty"`
+	Policy  *ConfigurationV2_Policy  `json:"policy,omitempty" yaml:"policy,omitempty"`
+
+	// LogOutput is the path the notarization log
+	// of the package is saved to, if empty it is
+	// saved next to the package.
+	LogOutput string `json:"log_output,omitempty" yaml:"log_output,omitempty"`
+}
+
+// GetTimeout returns the parsed Timeout,
+// zero if no timeout is set.
+func (settings *ConfigurationV3_Settings) GetTimeout() (time.Duration, error) {
+	if len(settings.Timeout) == 0 {
+		return 0, nil
+	}
+
+	timeout, err := time.ParseDuration(settings.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("parse timeout: %w", err)
+	}
+
+	return timeout, nil
+}
+
+// GetStaple reports if the notarization
+// ticket should be stapled to the package.
+func (settings *ConfigurationV3_Settings) GetStaple() bool {
+	return settings.Staple != nil && *settings.Staple
+}
+
+// merge returns the settings with each setting
+// that is unset taken from defaults.
+func (settings ConfigurationV3_Settings) merge(defaults ConfigurationV3_Settings) ConfigurationV3_Settings {
+	if settings.NotaryAuth == nil && len(settings.AuthProfile) == 0 {
+		settings.NotaryAuth = defaults.NotaryAuth
+		settings.AuthProfile = defaults.AuthProfile
+	}
+
+	if len(settings.Timeout) == 0 {
+		settings.Timeout = defaults.Timeout
+	}
+
+	if settings.Staple == nil {
+		settings.Staple = defaults.Staple
+	}
+
+	if settings.Archive == nil {
+		settings.Archive = defaults.Archive
+	}
+
+	if settings.Policy == nil {
+		settings.Policy = defaults.Policy
+	}
+
+	if len(settings.LogOutput) == 0 {
+		settings.LogOutput = defaults.LogOutput
+	}
+
+	return settings
+}
+
+type ConfigurationV3_Package struct {
+	// File is the path of the package, or a glob
+	// pattern matching several packages that
+	// share the settings of the entry.
+	File     string `json:"file" yaml:"file"`
+	BundleID string `json:"bundle_id" yaml:"bundle_id"`
+
+	// Exclude lists glob patterns of paths
+	// matched by File that aren't packages.
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+
+	ConfigurationV3_Settings `yaml:",inline"`
+}
+
+func (config *ConfigurationV3) GetPackages() []Package {
+	packages := make([]Package, 0, len(config.Packages))
+	for i := range config.Packages {
+		settings := config.Settings(&config.Packages[i])
+
+		packages = append(packages, Package{
+			File:     config.Packages[i].File,
+			BundleID: config.Packages[i].BundleID,
+			Staple:   settings.GetStaple(),
+		})
+	}
+
+	return packages
+}
+
+// Settings returns the settings for pkg merged over
+// the configured defaults, with NotaryAuth set to the
+// selected profile if AuthProfile is set.
+func (config *ConfigurationV3) Settings(pkg *ConfigurationV3_Package) ConfigurationV3_Settings {
+	settings := pkg.ConfigurationV3_Settings.merge(config.Defaults)
+	if settings.NotaryAuth == nil && len(settings.AuthProfile) > 0 {
+		settings.NotaryAuth = config.NotaryAuthProfiles[settings.AuthProfile]
+	}
+
+	return settings
+}
+
+func (_ *ConfigurationV3) _isConfig() {}
+
+// ResolveSecrets resolves the secret references in the
+// auth settings used by each package, so that a missing
+// secret is reported before any package is submitted.
+// Secrets fetched over the network are requested with
+// the network settings of the configuration.
+func (config *ConfigurationV3) ResolveSecrets(ctx context.Context) error {
+	httpClient, err := config.Network.HTTPClient()
+	if err != nil {
+		return fmt.Errorf("apply network configuration: %w", err)
+	}
+
+	ctx = secret.WithHTTPClient(ctx, httpClient)
+
+	resolved := make(map[*ConfigurationV2_NotaryAuth]bool)
+	for i := range config.Packages {
+		auth := config.Settings(&config.Packages[i]).NotaryAuth
+		if auth == nil || resolved[auth] {
+			continue
+		}
+
+		if err = auth.resolveSecrets(ctx); err != nil {
+			return fmt.Errorf("package '%s': %w", config.Packages[i].File, err)
+		}
+
+		resolved[auth] = true
+	}
+
+	return nil
+}
+
+// expandPackages replaces each package whose file is a
+// glob pattern with a package for each matching path,
+// with the same settings, less those excluded.
+func (config *ConfigurationV3) expandPackages() error {
+	var (
+		expanded []ConfigurationV3_Package
+		errs     []error
+	)
+
+	for _, pkg := range config.Packages {
+		if !glob.HasMeta(pkg.File) && len(pkg.Exclude) == 0 {
+			expanded = append(expanded, pkg)
+			continue
+		}
+
+		files, err := expandPackageFile(pkg.File, pkg.Exclude)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, file := range files {
+			match := pkg
+			match.File = file
+			match.Exclude = nil
+
+			expanded = append(expanded, match)
+		}
+	}
+
+	config.Packages = expanded
+	return errors.Join(errs...)
+}
+
+func expandPackageFile(pattern string, exclude []string) ([]string, error) {
+	for _, excludePattern := range exclude {
+		if err := glob.Validate(excludePattern); err != nil {
+			return nil, fmt.Errorf("package '%s': invalid exclude pattern '%s': %w", pattern, excludePattern, err)
+		}
+	}
+
+	var matches []string
+	if glob.HasMeta(pattern) {
+		var err error
+		if matches, err = glob.Expand(pattern); err != nil {
+			return nil, fmt.Errorf("package '%s': expand pattern: %w", pattern, err)
+		}
+	} else {
+		matches = []string{pattern}
+	}
+
+	files := slices.DeleteFunc(slices.Clone(matches), func(file string) bool {
+		return slices.ContainsFunc(exclude, func(excludePattern string) bool {
+			return glob.Match(path.Clean(excludePattern), filepath.ToSlash(filepath.Clean(file)))
+		})
+	})
+
+	switch {
+	case len(matches) == 0:
+		return nil, fmt.Errorf("package '%s': pattern matched no files", pattern)
+
+	case len(files) == 0:
+		return nil, fmt.Errorf("package '%s': every file matched by the pattern is excluded", pattern)
+	}
+
+	return files, nil
+}
+
+// validate checks the settings of each package
+// once merged with the defaults, so that mistakes
+// are reported before any package is submitted.
+func (config *ConfigurationV3) validate() error {
+	var errs []error
+	if config.Defaults.NotaryAuth != nil && len(config.Defaults.AuthProfile) > 0 {
+		errs = append(errs, errors.New("defaults: only one of notary_auth or auth_profile can be specified"))
+	}
+
+	for name, profile := range config.NotaryAuthProfiles {
+		if profile == nil {
+			errs = append(errs, fmt.Errorf("notary auth profile '%s' is empty", name))
+		}
+	}
+
+	for i := range config.Packages {
+		pkg := &config.Packages[i]
+		settings := config.Settings(pkg)
+
+		if len(pkg.File) == 0 {
+			errs = append(errs, fmt.Errorf("package %d: no file specified", i))
+			continue
+		}
+
+		switch {
+		case pkg.NotaryAuth != nil && len(pkg.AuthProfile) > 0:
+			errs = append(errs, fmt.Errorf("package '%s': only one of notary_auth or auth_profile can be specified", pkg.File))
+
+		case settings.NotaryAuth == nil && len(settings.AuthProfile) > 0:
+			errs = append(errs, fmt.Errorf("package '%s': unknown auth profile '%s'", pkg.File, settings.AuthProfile))
+
+		case settings.NotaryAuth == nil:
+			errs = append(errs, fmt.Errorf("package '%s': no notary_auth or auth_profile specified for the package or in defaults", pkg.File))
+		}
+
+		if _, err := settings.GetTimeout(); err != nil {
+			errs = append(errs, fmt.Errorf("package '%s': %w", pkg.File, err))
+		}
+
+		if settings.Archive != nil {
+			if err := settings.Archive.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("package '%s': %w", pkg.File, err))
+			}
+		}
+
+		if _, err := settings.Policy.Resolve(); err != nil {
+			errs = append(errs, fmt.Errorf("package '%s': policy: %w", pkg.File, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
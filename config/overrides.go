@@ -0,0 +1,92 @@
+package config
+
+import (
+	"errors"
+	"os"
+)
+
+const (
+	// EnvNotaryKeyId is the environment variable
+	// holding the ID of the App Store Connect key.
+	EnvNotaryKeyId = "NOTARY_KEY_ID"
+
+	// EnvNotaryKey is the environment variable holding
+	// the App Store Connect key, PEM or base64 encoded.
+	EnvNotaryKey = "NOTARY_KEY"
+
+	// EnvNotaryIssuer is the environment variable
+	// holding the ID of the team that issued the
+	// App Store Connect key, if it is a team key.
+	EnvNotaryIssuer = "NOTARY_ISSUER"
+)
+
+// Overrides are settings given outside of the configuration
+// file, by environment variables or on the command line. They
+// take precedence over the configuration file, and allow the
+// utility to be configured without one:
+//
+//   - NotaryAuth replaces the notary_auth or auth_profile in
+//     the defaults, packages with their own keep them
+//   - Files replace the packages in the configuration file,
+//     the packages use the defaults in the file
+//   - Staple replaces the staple setting of every package
+type Overrides struct {
+	NotaryAuth *ConfigurationV2_NotaryAuth
+	Files      []string
+	Staple     *bool
+}
+
+// IsEmpty reports if there are no overrides.
+func (overrides Overrides) IsEmpty() bool {
+	return overrides.NotaryAuth == nil && len(overrides.Files) == 0 && overrides.Staple == nil
+}
+
+// EnvironmentOverrides returns the overrides set by the
+// NOTARY_KEY_ID, NOTARY_KEY, and NOTARY_ISSUER environment
+// variables, NotaryAuth is nil if none are set.
+func EnvironmentOverrides() (Overrides, error) {
+	keyId, key, issuer := os.Getenv(EnvNotaryKeyId), os.Getenv(EnvNotaryKey), os.Getenv(EnvNotaryIssuer)
+
+	switch {
+	case len(keyId) == 0 && len(key) == 0 && len(issuer) == 0:
+		return Overrides{}, nil
+
+	case len(keyId) == 0 || len(key) == 0:
+		return Overrides{}, errors.New("both " + EnvNotaryKeyId + " and " + EnvNotaryKey + " must be set to use an App Store Connect key from the environment")
+	}
+
+	// The key is left as a reference so that, like a key
+	// in the configuration file, it is only read when the
+	// secrets are resolved
+	auth := &ConfigurationV2_NotaryAuth{
+		KeyId:   keyId,
+		KeyFile: "env:" + EnvNotaryKey,
+	}
+
+	if len(issuer) > 0 {
+		auth.KeyIssuerId = &issuer
+	}
+
+	return Overrides{NotaryAuth: auth}, nil
+}
+
+// apply applies the overrides to the configuration.
+func (overrides Overrides) apply(config *ConfigurationV3) {
+	if overrides.NotaryAuth != nil {
+		config.Defaults.NotaryAuth = overrides.NotaryAuth
+		config.Defaults.AuthProfile = ""
+	}
+
+	if len(overrides.Files) > 0 {
+		config.Packages = make([]ConfigurationV3_Package, 0, len(overrides.Files))
+		for _, file := range overrides.Files {
+			config.Packages = append(config.Packages, ConfigurationV3_Package{File: file})
+		}
+	}
+
+	if overrides.Staple != nil {
+		for i := range config.Packages {
+			config.Packages[i].Staple = overrides.Staple
+		}
+	}
+}
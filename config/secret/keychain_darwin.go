@@ -1,6 +1,6 @@
 //go:build darwin && !disable_keychain
 
-package config
+package secret
 
 /*
 #cgo LDFLAGS: -framework CoreFoundation -framework SecurityFoundation
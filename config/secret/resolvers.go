@@ -0,0 +1,64 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// execTimeout limits how long a helper
+// command can take to output a secret.
+const execTimeout = 30 * time.Second
+
+func resolveEnv(_ context.Context, name string) (string, error) {
+	value, found := os.LookupEnv(name)
+	if !found {
+		return "", fmt.Errorf("environment variable '%s' is not set", name)
+	}
+
+	return value, nil
+}
+
+func resolveFile(_ context.Context, path string) (string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+
+	return strings.TrimRight(string(contents), "\r\n"), nil
+}
+
+// resolveExec runs the command, which is split on
+// whitespace and not interpreted by a shell, and
+// returns its output less any trailing newline.
+func resolveExec(ctx context.Context, command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("no command specified")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, execTimeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	// NOTE: The output isn't included in the
+	//       error as it may contain the secret
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("run secret helper '%s': %w", args[0], err)
+	}
+
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+func resolveKeychain(_ context.Context, label string) (string, error) {
+	return getPasswordFromKeychain(label)
+}
@@ -0,0 +1,189 @@
+// Package secret resolves references to secrets used in
+// the configuration, such as "env:NOTARY_KEY_ID", to their
+// values, so that sensitive values don't need to be written
+// in the configuration file itself.
+//
+// A value is a reference if it starts with the scheme of a
+// registered Resolver followed by a colon, any other value
+// is used literally. A literal value that itself starts with
+// a scheme, such as a password beginning "exec:", is written
+// with LiteralPrefix in front of it, "literal:exec:...". The
+// following schemes are registered by default:
+//
+//   - env:<name>, the value of an environment variable
+//   - file:<path>, the contents of a file
+//   - exec:<command> [args...], the output of a helper command
+//   - vault:<path>#<field>, a field of a HashiCorp Vault KV secret
+//   - keychain:<label>, a generic password in the macOS Keychain
+//   - secret-service:<name>=<value>[&...], the item with these
+//     attributes in a freedesktop Secret Service, such as
+//     GNOME Keyring or KWallet, on Linux
+//
+// For backward compatibility "ENV:<name>" and "@keychain:<label>"
+// are also accepted, as is "@secret-service:" to match.
+//
+// Resolved values must never be logged, errors returned by
+// this package only describe the reference.
+//
+// Resolvers that make HTTP requests, such as that for Vault,
+// use the client given with WithHTTPClient so that the same
+// network settings apply to them as to the Notary API.
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// LiteralPrefix marks a value as literal, it is removed
+// from the value which is never treated as a reference.
+const LiteralPrefix = "literal:"
+
+// ErrUnknownScheme is returned by Resolve when
+// a reference uses a scheme with no resolver.
+var ErrUnknownScheme = errors.New("unknown secret reference scheme")
+
+// Resolver resolves references for a scheme,
+// ref is the reference without the scheme.
+type Resolver interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// ResolverFunc adapts a function to a Resolver.
+type ResolverFunc func(ctx context.Context, ref string) (string, error)
+
+func (fn ResolverFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return fn(ctx, ref)
+}
+
+var (
+	resolversLock sync.RWMutex
+	resolvers     = map[string]Resolver{
+		"env":            ResolverFunc(resolveEnv),
+		"file":           ResolverFunc(resolveFile),
+		"exec":           ResolverFunc(resolveExec),
+		"vault":          new(VaultResolver),
+		"keychain":       ResolverFunc(resolveKeychain),
+		"secret-service": defaultSecretServiceResolver,
+	}
+
+	legacyPrefixes = map[string]string{
+		"ENV:":             "env",
+		"@keychain:":       "keychain",
+		"@secret-service:": "secret-service",
+	}
+
+	// remoteSchemes are the schemes whose
+	// resolvers fetch secrets over the network.
+	remoteSchemes = []string{"vault"}
+
+	// commandSchemes are the schemes whose
+	// resolvers run a command.
+	commandSchemes = []string{"exec"}
+)
+
+type httpClientKey struct{}
+
+// WithHTTPClient returns a copy of ctx in which
+// resolvers make HTTP requests with client.
+func WithHTTPClient(ctx context.Context, client *http.Client) context.Context {
+	return context.WithValue(ctx, httpClientKey{}, client)
+}
+
+// httpClient returns the client given to
+// WithHTTPClient, or http.DefaultClient if
+// there isn't one.
+func httpClient(ctx context.Context) *http.Client {
+	if client, ok := ctx.Value(httpClientKey{}).(*http.Client); ok && client != nil {
+		return client
+	}
+
+	return http.DefaultClient
+}
+
+// Register sets the resolver used for scheme,
+// replacing any existing resolver.
+func Register(scheme string, resolver Resolver) {
+	resolversLock.Lock()
+	defer resolversLock.Unlock()
+
+	resolvers[scheme] = resolver
+}
+
+// parse splits value into the resolver for
+// its scheme and the rest of the reference,
+// the resolver is nil if value isn't a
+// reference, in which case the rest is the
+// literal value.
+func parse(value string) (string, Resolver, string) {
+	if literal, found := strings.CutPrefix(value, LiteralPrefix); found {
+		return "", nil, literal
+	}
+
+	for prefix, scheme := range legacyPrefixes {
+		if ref, found := strings.CutPrefix(value, prefix); found {
+			return scheme, lookup(scheme), ref
+		}
+	}
+
+	scheme, ref, found := strings.Cut(value, ":")
+	if !found {
+		return "", nil, value
+	}
+
+	resolver := lookup(scheme)
+	if resolver == nil {
+		return "", nil, value
+	}
+
+	return scheme, resolver, ref
+}
+
+func lookup(scheme string) Resolver {
+	resolversLock.RLock()
+	defer resolversLock.RUnlock()
+
+	return resolvers[scheme]
+}
+
+// IsReference reports if value is a
+// reference to be resolved.
+func IsReference(value string) bool {
+	_, resolver, _ := parse(value)
+	return resolver != nil
+}
+
+// IsRemote reports if value is a reference to
+// a secret that is fetched over the network.
+func IsRemote(value string) bool {
+	scheme, resolver, _ := parse(value)
+	return resolver != nil && slices.Contains(remoteSchemes, scheme)
+}
+
+// IsCommand reports if value is a reference to a
+// secret that is output by running a command.
+func IsCommand(value string) bool {
+	scheme, resolver, _ := parse(value)
+	return resolver != nil && slices.Contains(commandSchemes, scheme)
+}
+
+// Resolve returns the secret value refers to, or value
+// itself, less any LiteralPrefix, if it isn't a reference.
+func Resolve(ctx context.Context, value string) (string, error) {
+	scheme, resolver, ref := parse(value)
+	if resolver == nil {
+		return ref, nil
+	}
+
+	resolved, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s secret reference: %w", scheme, err)
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package secret
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+var defaultSecretServiceResolver Resolver = ResolverFunc(func(context.Context, string) (string, error) {
+	return "", fmt.Errorf("platform '%s' doesn't support Secret Service access", runtime.GOOS)
+})
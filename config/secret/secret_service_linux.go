@@ -0,0 +1,204 @@
+//go:build linux
+
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	secretServiceName      = "org.freedesktop.secrets"
+	secretServicePath      = dbus.ObjectPath("/org/freedesktop/secrets")
+	secretServiceInterface = "org.freedesktop.Secret.Service"
+	secretPromptInterface  = "org.freedesktop.Secret.Prompt"
+	secretSessionInterface = "org.freedesktop.Secret.Session"
+
+	// noPrompt is the path returned in place of
+	// a prompt when no prompt is required.
+	noPrompt = dbus.ObjectPath("/")
+)
+
+var defaultSecretServiceResolver Resolver = new(SecretServiceResolver)
+
+// SecretServiceResolver resolves references to the secret
+// of an item stored by a freedesktop Secret Service, such as
+// GNOME Keyring or KWallet, unlocking the item if needed.
+//
+// Items are found by their attributes, as they are by
+// "secret-tool lookup", which a reference gives as a query
+// string such as "service=notarization-helper&account=key_id".
+type SecretServiceResolver struct {
+	// Conn is the D-Bus connection the Secret Service
+	// is called on, if nil the session bus is used.
+	Conn *dbus.Conn
+}
+
+// secretServiceSecret is the Secret struct
+// of the Secret Service D-Bus API.
+type secretServiceSecret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+func (resolver *SecretServiceResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	attributes, err := parseSecretServiceAttributes(ref)
+	if err != nil {
+		return "", err
+	}
+
+	conn := resolver.Conn
+	if conn == nil {
+		if conn, err = dbus.SessionBus(); err != nil {
+			return "", fmt.Errorf("connect to D-Bus session bus: %w", err)
+		}
+	}
+
+	service := conn.Object(secretServiceName, secretServicePath)
+
+	// The plain algorithm transfers the secret unencrypted,
+	// which is what the session bus, only accessible to
+	// the user, is relied on for by most clients
+	var (
+		output  dbus.Variant
+		session dbus.ObjectPath
+	)
+
+	if err = service.CallWithContext(ctx, secretServiceInterface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session); err != nil {
+		return "", fmt.Errorf("open Secret Service session: %w", err)
+	}
+	defer conn.Object(secretServiceName, session).CallWithContext(ctx, secretSessionInterface+".Close", 0)
+
+	item, locked, err := resolver.findItem(ctx, service, attributes)
+	if err != nil {
+		return "", fmt.Errorf("find item '%s': %w", ref, err)
+	}
+
+	if locked {
+		if err = resolver.unlock(ctx, conn, service, item); err != nil {
+			return "", fmt.Errorf("unlock item '%s': %w", ref, err)
+		}
+	}
+
+	var secrets map[dbus.ObjectPath]secretServiceSecret
+	if err = service.CallWithContext(ctx, secretServiceInterface+".GetSecrets", 0, []dbus.ObjectPath{item}, session).Store(&secrets); err != nil {
+		return "", fmt.Errorf("get secret of item '%s': %w", ref, err)
+	}
+
+	secret, found := secrets[item]
+	if !found {
+		return "", fmt.Errorf("Secret Service returned no secret for item '%s'", ref)
+	}
+
+	return string(secret.Value), nil
+}
+
+// parseSecretServiceAttributes parses the attributes
+// of a reference, given as a query string, at least
+// one attribute is required as an empty search
+// matches every item.
+func parseSecretServiceAttributes(ref string) (map[string]string, error) {
+	if !strings.Contains(ref, "=") {
+		return nil, errors.New("secret-service reference must be attributes in the form <name>=<value>[&<name>=<value>...]")
+	}
+
+	query, err := url.ParseQuery(ref)
+	if err != nil {
+		return nil, fmt.Errorf("parse secret-service reference attributes: %w", err)
+	}
+
+	attributes := make(map[string]string, len(query))
+	for name, values := range query {
+		if len(name) == 0 {
+			return nil, errors.New("secret-service reference has an attribute with no name")
+		} else if len(values) > 1 {
+			return nil, fmt.Errorf("secret-service reference has more than one value for attribute '%s'", name)
+		}
+
+		attributes[name] = values[0]
+	}
+
+	return attributes, nil
+}
+
+// findItem returns the item matching attributes and
+// if it is locked, preferring an unlocked item if
+// more than one matches as "secret-tool lookup" does.
+func (resolver *SecretServiceResolver) findItem(ctx context.Context, service dbus.BusObject, attributes map[string]string) (dbus.ObjectPath, bool, error) {
+	var unlocked, locked []dbus.ObjectPath
+	if err := service.CallWithContext(ctx, secretServiceInterface+".SearchItems", 0, attributes).Store(&unlocked, &locked); err != nil {
+		return "", false, fmt.Errorf("search Secret Service items: %w", err)
+	}
+
+	switch {
+	case len(unlocked) > 0:
+		return unlocked[0], false, nil
+
+	case len(locked) > 0:
+		return locked[0], true, nil
+
+	default:
+		return "", false, errors.New("no Secret Service item has the attributes")
+	}
+}
+
+// unlock unlocks item, waiting for the user to
+// complete the prompt if the service shows one.
+func (resolver *SecretServiceResolver) unlock(ctx context.Context, conn *dbus.Conn, service dbus.BusObject, item dbus.ObjectPath) error {
+	var (
+		unlocked []dbus.ObjectPath
+		prompt   dbus.ObjectPath
+	)
+
+	if err := service.CallWithContext(ctx, secretServiceInterface+".Unlock", 0, []dbus.ObjectPath{item}).Store(&unlocked, &prompt); err != nil {
+		return err
+	} else if prompt == noPrompt {
+		return nil
+	}
+
+	// Subscribe to the result of the prompt
+	// before it is shown so it can't be missed
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(secretPromptInterface),
+		dbus.WithMatchMember("Completed"),
+	}
+
+	if err := conn.AddMatchSignalContext(ctx, match...); err != nil {
+		return fmt.Errorf("subscribe to prompt: %w", err)
+	}
+	defer conn.RemoveMatchSignalContext(ctx, match...)
+
+	signals := make(chan *dbus.Signal, 1)
+	conn.Signal(signals)
+	defer conn.RemoveSignal(signals)
+
+	if err := conn.Object(secretServiceName, prompt).CallWithContext(ctx, secretPromptInterface+".Prompt", 0, "").Err; err != nil {
+		return fmt.Errorf("show prompt: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case signal := <-signals:
+			if signal.Path != prompt || signal.Name != secretPromptInterface+".Completed" {
+				continue
+			}
+
+			if len(signal.Body) > 0 && signal.Body[0] == true {
+				return errors.New("prompt was dismissed")
+			}
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package secret_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/config/secret"
+	"github.com/KatelynHaworth/notarization-helper/v2/config/secret/vaulttest"
+)
+
+func TestResolve(t *testing.T) {
+	t.Setenv("SECRET_TEST_VALUE", "from the environment")
+
+	file := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(file, []byte("from a file\n"), 0600); err != nil {
+		t.Fatalf("write secret file: %v", err)
+	}
+
+	tests := []struct {
+		value, expected string
+	}{
+		{"plain value", "plain value"},
+		{"https://example.com", "https://example.com"},
+		{"env:SECRET_TEST_VALUE", "from the environment"},
+		{"ENV:SECRET_TEST_VALUE", "from the environment"},
+		{"file:" + file, "from a file"},
+
+		// Escaped values are never resolved,
+		// even with a registered scheme
+		{"literal:exec:false", "exec:false"},
+		{"literal:env:SECRET_TEST_VALUE", "env:SECRET_TEST_VALUE"},
+		{"literal:literal:value", "literal:value"},
+	}
+
+	for _, test := range tests {
+		resolved, err := secret.Resolve(context.Background(), test.value)
+		if err != nil {
+			t.Errorf("Resolve(%q) returned error: %v", test.value, err)
+		} else if resolved != test.expected {
+			t.Errorf("Resolve(%q) = %q, expected %q", test.value, resolved, test.expected)
+		}
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	for _, value := range []string{
+		"env:SECRET_TEST_UNSET",
+		"file:" + filepath.Join(t.TempDir(), "missing"),
+		"exec:",
+		"vault:secret/data/notary",
+	} {
+		if _, err := secret.Resolve(context.Background(), value); err == nil {
+			t.Errorf("Resolve(%q) expected an error", value)
+		}
+	}
+}
+
+func TestResolveExec(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("echo isn't available")
+	}
+
+	resolved, err := secret.Resolve(context.Background(), "exec:echo from a command")
+	if err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	} else if resolved != "from a command" {
+		t.Errorf("Resolve() = %q, expected %q", resolved, "from a command")
+	}
+}
+
+func TestReferenceKinds(t *testing.T) {
+	tests := []struct {
+		value                        string
+		reference, remote, isCommand bool
+	}{
+		{"plain value", false, false, false},
+		{"env:NAME", true, false, false},
+		{"@keychain:label", true, false, false},
+		{"vault:secret/data/notary#key_id", true, true, false},
+		{"exec:helper --key", true, false, true},
+		{"literal:exec:helper", false, false, false},
+		{"literal:vault:secret", false, false, false},
+	}
+
+	for _, test := range tests {
+		if reference := secret.IsReference(test.value); reference != test.reference {
+			t.Errorf("IsReference(%q) = %v, expected %v", test.value, reference, test.reference)
+		}
+
+		if remote := secret.IsRemote(test.value); remote != test.remote {
+			t.Errorf("IsRemote(%q) = %v, expected %v", test.value, remote, test.remote)
+		}
+
+		if isCommand := secret.IsCommand(test.value); isCommand != test.isCommand {
+			t.Errorf("IsCommand(%q) = %v, expected %v", test.value, isCommand, test.isCommand)
+		}
+	}
+}
+
+func TestVaultResolver(t *testing.T) {
+	srv := vaulttest.NewServer("test-token", map[string]map[string]string{
+		"notary": {"key_id": "ABC123"},
+	})
+	defer srv.Close()
+
+	resolver := srv.Resolver()
+	if value, err := resolver.Resolve(context.Background(), "secret/data/notary#key_id"); err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	} else if value != "ABC123" {
+		t.Errorf("Resolve() = %q, expected %q", value, "ABC123")
+	}
+
+	for _, ref := range []string{
+		"secret/data/notary#missing",
+		"secret/data/missing#key_id",
+		"secret/data/notary",
+		"#key_id",
+	} {
+		if _, err := resolver.Resolve(context.Background(), ref); err == nil {
+			t.Errorf("Resolve(%q) expected an error", ref)
+		}
+	}
+
+	resolver.Token = "wrong-token"
+	if _, err := resolver.Resolve(context.Background(), "secret/data/notary#key_id"); err == nil {
+		t.Error("expected an error for a token Vault doesn't accept")
+	} else if strings.Contains(err.Error(), "ABC123") {
+		t.Errorf("error contains the secret: %v", err)
+	}
+}
+
+func TestVaultResolverEnvironment(t *testing.T) {
+	srv := vaulttest.NewServer("test-token", map[string]map[string]string{
+		"notary": {"key_id": "ABC123"},
+	})
+	defer srv.Close()
+
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "test-token")
+
+	value, err := secret.Resolve(context.Background(), "vault:secret/data/notary#key_id")
+	if err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	} else if value != "ABC123" {
+		t.Errorf("Resolve() = %q, expected %q", value, "ABC123")
+	}
+}
+
+// recordingTransport records the requests sent
+// through it, so a test can check which client
+// a resolver used.
+type recordingTransport struct {
+	requests int
+}
+
+func (transport *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestVaultResolverContextClient(t *testing.T) {
+	srv := vaulttest.NewServer("test-token", map[string]map[string]string{
+		"notary": {"key_id": "ABC123"},
+	})
+	defer srv.Close()
+
+	transport := new(recordingTransport)
+	ctx := secret.WithHTTPClient(context.Background(), &http.Client{Transport: transport})
+
+	resolver := &secret.VaultResolver{Address: srv.URL, Token: "test-token"}
+	if _, err := resolver.Resolve(ctx, "secret/data/notary#key_id"); err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	} else if transport.requests != 1 {
+		t.Errorf("expected the request to be sent with the client from the context, %d requests were", transport.requests)
+	}
+}
@@ -0,0 +1,273 @@
+//go:build linux
+
+// Package secretservicetest provides a stand-in for a
+// freedesktop Secret Service, for testing secret references
+// without GNOME Keyring or KWallet. The stand-in is served on
+// a private bus started with dbus-daemon, so it doesn't
+// interfere with the Secret Service of the session.
+package secretservicetest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/config/secret"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	serviceName      = "org.freedesktop.secrets"
+	servicePath      = dbus.ObjectPath("/org/freedesktop/secrets")
+	serviceInterface = "org.freedesktop.Secret.Service"
+	sessionInterface = "org.freedesktop.Secret.Session"
+	sessionPath      = dbus.ObjectPath("/org/freedesktop/secrets/session/1")
+)
+
+// Item is an item stored by the stand-in.
+type Item struct {
+	Label      string
+	Attributes map[string]string
+	Secret     string
+}
+
+// Server holds a collection of items, each of which
+// starts locked and is unlocked without a prompt.
+type Server struct {
+	// Address of the private bus the
+	// stand-in is served on.
+	Address string
+
+	daemon *exec.Cmd
+	dir    string
+	conn   *dbus.Conn
+	client *dbus.Conn
+
+	lock     sync.Mutex
+	paths    []dbus.ObjectPath
+	items    map[dbus.ObjectPath]Item
+	unlocked map[dbus.ObjectPath]bool
+}
+
+// NewServer starts a private bus, which requires dbus-daemon,
+// and serves the stand-in holding items on it. The Server
+// must be closed once it is no longer needed.
+func NewServer(items ...Item) (*Server, error) {
+	srv := &Server{
+		items:    make(map[dbus.ObjectPath]Item),
+		unlocked: make(map[dbus.ObjectPath]bool),
+	}
+
+	for i, item := range items {
+		path := dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/secrets/collection/login/%d", i+1))
+		srv.paths, srv.items[path] = append(srv.paths, path), item
+	}
+
+	if err := srv.start(); err != nil {
+		_ = srv.Close()
+		return nil, err
+	}
+
+	return srv, nil
+}
+
+// start starts the private bus and exports the
+// stand-in on a connection to it, along with a
+// second connection used by resolvers.
+func (srv *Server) start() error {
+	var err error
+	if srv.dir, err = os.MkdirTemp("", "secretservicetest"); err != nil {
+		return fmt.Errorf("create bus directory: %w", err)
+	}
+
+	srv.daemon = exec.Command("dbus-daemon", "--session", "--nofork", "--print-address",
+		"--address=unix:path="+filepath.Join(srv.dir, "bus"))
+
+	stdout, err := srv.daemon.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("create dbus-daemon output pipe: %w", err)
+	} else if err = srv.daemon.Start(); err != nil {
+		return fmt.Errorf("start dbus-daemon: %w", err)
+	}
+
+	// The address is printed once
+	// the bus accepts connections
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("read bus address: %w", err)
+	}
+
+	srv.Address = strings.TrimSpace(address)
+	if srv.conn, err = dbus.Connect(srv.Address); err != nil {
+		return fmt.Errorf("connect stand-in to bus: %w", err)
+	} else if err = srv.export(); err != nil {
+		return err
+	} else if srv.client, err = dbus.Connect(srv.Address); err != nil {
+		return fmt.Errorf("connect resolver to bus: %w", err)
+	}
+
+	return nil
+}
+
+func (srv *Server) export() error {
+	if err := srv.conn.ExportMethodTable(map[string]any{
+		"OpenSession": srv.openSession,
+		"SearchItems": srv.searchItems,
+		"Unlock":      srv.unlock,
+		"GetSecrets":  srv.getSecrets,
+	}, servicePath, serviceInterface); err != nil {
+		return fmt.Errorf("export service: %w", err)
+	}
+
+	if err := srv.conn.ExportMethodTable(map[string]any{
+		"Close": func() *dbus.Error { return nil },
+	}, sessionPath, sessionInterface); err != nil {
+		return fmt.Errorf("export session: %w", err)
+	}
+
+	reply, err := srv.conn.RequestName(serviceName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return fmt.Errorf("request bus name: %w", err)
+	} else if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("bus name '%s' is already owned", serviceName)
+	}
+
+	return nil
+}
+
+// Close closes the connections to the
+// private bus and stops the bus.
+func (srv *Server) Close() error {
+	var errs []error
+	for _, conn := range []*dbus.Conn{srv.client, srv.conn} {
+		if conn != nil {
+			errs = append(errs, conn.Close())
+		}
+	}
+
+	if srv.daemon != nil && srv.daemon.Process != nil {
+		_ = srv.daemon.Process.Kill()
+		_ = srv.daemon.Wait()
+	}
+
+	if len(srv.dir) > 0 {
+		errs = append(errs, os.RemoveAll(srv.dir))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Resolver returns a resolver for secrets stored
+// in the stand-in, which calls it over a connection
+// to the private bus owned by the Server.
+func (srv *Server) Resolver() *secret.SecretServiceResolver {
+	return &secret.SecretServiceResolver{Conn: srv.client}
+}
+
+// Unlocked reports if the item with label has been unlocked.
+func (srv *Server) Unlocked(label string) bool {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	for path, item := range srv.items {
+		if item.Label == label {
+			return srv.unlocked[path]
+		}
+	}
+
+	return false
+}
+
+func (srv *Server) openSession(algorithm string, _ dbus.Variant) (dbus.Variant, dbus.ObjectPath, *dbus.Error) {
+	if algorithm != "plain" {
+		return dbus.Variant{}, "", dbus.NewError("org.freedesktop.DBus.Error.NotSupported", []any{"unsupported algorithm " + algorithm})
+	}
+
+	return dbus.MakeVariant(""), sessionPath, nil
+}
+
+// searchItems returns the items that have all of the
+// attributes, in the order they were given to NewServer.
+func (srv *Server) searchItems(attributes map[string]string) ([]dbus.ObjectPath, []dbus.ObjectPath, *dbus.Error) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	unlocked, locked := []dbus.ObjectPath{}, []dbus.ObjectPath{}
+	for _, path := range srv.paths {
+		if !hasAttributes(srv.items[path].Attributes, attributes) {
+			continue
+		}
+
+		if srv.unlocked[path] {
+			unlocked = append(unlocked, path)
+		} else {
+			locked = append(locked, path)
+		}
+	}
+
+	return unlocked, locked, nil
+}
+
+func hasAttributes(itemAttributes, attributes map[string]string) bool {
+	for name, value := range attributes {
+		if itemValue, found := itemAttributes[name]; !found || itemValue != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (srv *Server) unlock(objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	unlocked := []dbus.ObjectPath{}
+	for _, path := range objects {
+		if _, found := srv.items[path]; found {
+			srv.unlocked[path] = true
+			unlocked = append(unlocked, path)
+		}
+	}
+
+	return unlocked, dbus.ObjectPath("/"), nil
+}
+
+// secretStruct is the Secret struct
+// of the Secret Service D-Bus API.
+type secretStruct struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+func (srv *Server) getSecrets(items []dbus.ObjectPath, session dbus.ObjectPath) (map[dbus.ObjectPath]secretStruct, *dbus.Error) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	if session != sessionPath {
+		return nil, dbus.NewError("org.freedesktop.Secret.Error.NoSession", []any{"no such session"})
+	}
+
+	secrets := make(map[dbus.ObjectPath]secretStruct)
+	for _, path := range items {
+		if !srv.unlocked[path] {
+			return nil, dbus.NewError("org.freedesktop.Secret.Error.IsLocked", []any{"item is locked"})
+		}
+
+		secrets[path] = secretStruct{
+			Session:     session,
+			Parameters:  []byte{},
+			Value:       []byte(srv.items[path].Secret),
+			ContentType: "text/plain",
+		}
+	}
+
+	return secrets, nil
+}
@@ -0,0 +1,122 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// VaultResolver resolves references to a field of a
+// secret stored in a HashiCorp Vault KV secrets engine,
+// written as "<path>#<field>". For version 2 of the KV
+// engine the path includes "data", for example
+// "secret/data/notary#key_id".
+type VaultResolver struct {
+	// Address of the Vault server, if
+	// empty VAULT_ADDR is used.
+	Address string
+
+	// Token used to authenticate with
+	// Vault, if empty VAULT_TOKEN is used.
+	Token string
+
+	// Namespace sent with requests, if
+	// empty VAULT_NAMESPACE is used.
+	Namespace string
+
+	// HTTPClient used to make requests, if nil
+	// the client given to WithHTTPClient is used
+	// or, failing that, http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+func (vault *VaultResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	secretPath, field, found := strings.Cut(ref, "#")
+	if !found || len(secretPath) == 0 || len(field) == 0 {
+		return "", errors.New("vault reference must be in the form <path>#<field>")
+	}
+
+	address := orEnv(vault.Address, "VAULT_ADDR")
+	if len(address) == 0 {
+		return "", errors.New("no Vault address configured, set VAULT_ADDR")
+	}
+
+	token := orEnv(vault.Token, "VAULT_TOKEN")
+	if len(token) == 0 {
+		return "", errors.New("no Vault token configured, set VAULT_TOKEN")
+	}
+
+	secretUrl, err := url.JoinPath(address, "v1", secretPath)
+	if err != nil {
+		return "", fmt.Errorf("build Vault URL: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, secretUrl, nil)
+	if err != nil {
+		return "", fmt.Errorf("create Vault request: %w", err)
+	}
+
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := orEnv(vault.Namespace, "VAULT_NAMESPACE"); len(namespace) > 0 {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	client := vault.HTTPClient
+	if client == nil {
+		client = httpClient(ctx)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request secret from Vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("read secret '%s' from Vault: unexpected status %s", secretPath, resp.Status)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode Vault response: %w", err)
+	}
+
+	fields := body.Data
+
+	// Version 2 of the KV engine nests the
+	// fields of the secret within "data"
+	if nested, ok := fields["data"]; ok {
+		var kv2 map[string]json.RawMessage
+		if err = json.Unmarshal(nested, &kv2); err == nil {
+			fields = kv2
+		}
+	}
+
+	rawValue, found := fields[field]
+	if !found {
+		return "", fmt.Errorf("secret '%s' in Vault has no field '%s'", secretPath, field)
+	}
+
+	var value string
+	if err = json.Unmarshal(rawValue, &value); err != nil {
+		return "", fmt.Errorf("field '%s' of secret '%s' in Vault isn't a string", field, secretPath)
+	}
+
+	return value, nil
+}
+
+func orEnv(value, envName string) string {
+	if len(value) > 0 {
+		return value
+	}
+
+	return os.Getenv(envName)
+}
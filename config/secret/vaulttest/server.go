@@ -0,0 +1,74 @@
+// Package vaulttest provides an in-process stand-in for the
+// HashiCorp Vault KV secrets engine, for testing secret
+// references without a Vault server.
+package vaulttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/config/secret"
+)
+
+// Server serves secrets from a KV version 2 engine
+// mounted at "secret", so the fields written to
+// "notary" are read using the reference
+// "vault:secret/data/notary#<field>".
+type Server struct {
+	*httptest.Server
+
+	token   string
+	secrets map[string]map[string]string
+}
+
+// NewServer starts a stand-in that only accepts
+// requests authenticated with token, secrets maps
+// the path of each secret to its fields.
+func NewServer(token string, secrets map[string]map[string]string) *Server {
+	srv := &Server{
+		token:   token,
+		secrets: secrets,
+	}
+
+	srv.Server = httptest.NewServer(http.HandlerFunc(srv.handle))
+	return srv
+}
+
+// Resolver returns a resolver for
+// secrets stored in the stand-in.
+func (srv *Server) Resolver() *secret.VaultResolver {
+	return &secret.VaultResolver{
+		Address:    srv.URL,
+		Token:      srv.token,
+		HTTPClient: srv.Client(),
+	}
+}
+
+func (srv *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"errors":["unsupported operation"]}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("X-Vault-Token") != srv.token {
+		http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+		return
+	}
+
+	path, found := strings.CutPrefix(r.URL.Path, "/v1/secret/data/")
+	fields, exists := srv.secrets[path]
+	if !found || !exists {
+		http.Error(w, `{"errors":[]}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"data": map[string]any{
+			"data":     fields,
+			"metadata": map[string]any{"version": 1},
+		},
+	})
+}
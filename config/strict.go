@@ -0,0 +1,557 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// DecodeError describes a problem at a
+// position in a configuration file.
+type DecodeError struct {
+	Line   int
+	Column int
+
+	// Path is the location of the problem
+	// in the configuration, such as
+	// "packages[0].staple".
+	Path    string
+	Message string
+}
+
+func (err *DecodeError) Error() string {
+	var position string
+	if err.Line > 0 {
+		position = fmt.Sprintf("line %d, column %d: ", err.Line, err.Column)
+	}
+
+	if len(err.Path) == 0 {
+		return position + err.Message
+	}
+
+	return fmt.Sprintf("%s%s: %s", position, err.Path, err.Message)
+}
+
+// parseNode parses data into a tree of nodes recording
+// the position of each value, so that problems in the
+// configuration can be reported with their position.
+func (format ConfigFormat) parseNode(data []byte) (*yaml.Node, error) {
+	switch format {
+	case ConfigFormatJSON:
+		return parseJSONNode(data)
+
+	case ConfigFormatTOML:
+		return parseTOMLNode(data)
+
+	case ConfigFormatYAML:
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+			return nil, err
+		}
+
+		return doc.Content[0], nil
+
+	default:
+		return nil, errors.New("unsupported config format")
+	}
+}
+
+// check reports every key under root that doesn't match a
+// field of dst, suggesting the closest field name, and every
+// value that doesn't match the type of its field, as the
+// decoders otherwise silently ignore unknown keys and only
+// report the first type mismatch.
+func (format ConfigFormat) check(root *yaml.Node, dst any) error {
+	if root == nil {
+		return nil
+	}
+
+	checker := fieldChecker{format: format}
+	checker.checkNode(root, reflect.TypeOf(dst), "")
+
+	return errors.Join(checker.errs...)
+}
+
+type fieldChecker struct {
+	format ConfigFormat
+	errs   []error
+}
+
+func (checker *fieldChecker) fail(node *yaml.Node, path, format string, args ...any) {
+	checker.errs = append(checker.errs, &DecodeError{
+		Line:    node.Line,
+		Column:  node.Column,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (checker *fieldChecker) checkNode(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			checker.fail(node, path, "expected %s but found %s", checker.describeType(t), checker.describeNode(node))
+			return
+		}
+
+		fields := checker.structFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if len(path) == 0 && key.Value == "config_version" {
+				continue
+			}
+
+			field, found := checker.lookupField(fields, key.Value)
+			if !found {
+				checker.unknownField(key, path, fields)
+				continue
+			}
+
+			checker.checkNode(value, field, joinPath(path, key.Value))
+		}
+
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			checker.fail(node, path, "expected %s but found %s", checker.describeType(t), checker.describeNode(node))
+			return
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checker.checkNode(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value))
+		}
+
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			checker.fail(node, path, "expected %s but found %s", checker.describeType(t), checker.describeNode(node))
+			return
+		}
+
+		for i, item := range node.Content {
+			checker.checkNode(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+
+	case reflect.Interface:
+		// Any value is accepted
+
+	default:
+		if !checker.scalarMatches(node, t) {
+			checker.fail(node, path, "expected %s but found %s", checker.describeType(t), checker.describeNode(node))
+		}
+	}
+}
+
+func (checker *fieldChecker) scalarMatches(node *yaml.Node, t reflect.Type) bool {
+	if node.Kind != yaml.ScalarNode {
+		return false
+	}
+
+	// YAML is decoded from the same nodes, so a scalar
+	// matches if it decodes into the type of its field,
+	// except that a quoted scalar is always a string even
+	// though yaml.v3 decodes "yes" into a boolean
+	if checker.format == ConfigFormatYAML {
+		quoted := node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) != 0
+		if quoted && t.Kind() != reflect.String {
+			return false
+		}
+
+		return node.Decode(reflect.New(t).Interface()) == nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return node.Tag == "!!str"
+
+	case reflect.Bool:
+		return node.Tag == "!!bool"
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return node.Tag == "!!int"
+
+	case reflect.Float32, reflect.Float64:
+		return node.Tag == "!!int" || node.Tag == "!!float"
+
+	default:
+		return true
+	}
+}
+
+func (checker *fieldChecker) unknownField(key *yaml.Node, path string, fields map[string]reflect.Type) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+
+	if suggestion := suggestField(key.Value, names); len(suggestion) > 0 {
+		checker.fail(key, path, "unknown field '%s', did you mean '%s'?", key.Value, suggestion)
+	} else {
+		checker.fail(key, path, "unknown field '%s'", key.Value)
+	}
+}
+
+// structFields returns the type of each field of t by its
+// name in the configuration, including those of embedded
+// structs that are inlined.
+func (checker *fieldChecker) structFields(t reflect.Type) map[string]reflect.Type {
+	// TOML is decoded through JSON and
+	// so uses the same field names
+	tagKey := "json"
+	if checker.format == ConfigFormatYAML {
+		tagKey = "yaml"
+	}
+
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get(tagKey), ",")
+		if name == "-" {
+			continue
+		}
+
+		inline := slices.Contains(strings.Split(opts, ","), "inline") ||
+			(tagKey == "json" && field.Anonymous && len(name) == 0)
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if inline && fieldType.Kind() == reflect.Struct {
+			for name, inlineType := range checker.structFields(fieldType) {
+				fields[name] = inlineType
+			}
+
+			continue
+		}
+
+		if len(name) == 0 && tagKey == "yaml" {
+			name = strings.ToLower(field.Name)
+		} else if len(name) == 0 {
+			name = field.Name
+		}
+
+		fields[name] = field.Type
+	}
+
+	return fields
+}
+
+func (checker *fieldChecker) lookupField(fields map[string]reflect.Type, name string) (reflect.Type, bool) {
+	if field, found := fields[name]; found || checker.format == ConfigFormatYAML {
+		return field, found
+	}
+
+	// encoding/json matches keys to field
+	// names without regard to case
+	for fieldName, field := range fields {
+		if strings.EqualFold(fieldName, name) {
+			return field, true
+		}
+	}
+
+	return nil, false
+}
+
+func (checker *fieldChecker) describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return checker.describeMapping()
+
+	case reflect.Slice:
+		return "a list"
+
+	case reflect.String:
+		return "a string"
+
+	case reflect.Bool:
+		return "a boolean"
+
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+
+	default:
+		return "an integer"
+	}
+}
+
+func (checker *fieldChecker) describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return checker.describeMapping()
+
+	case yaml.SequenceNode:
+		return "a list"
+	}
+
+	switch node.Tag {
+	case "!!bool":
+		return "a boolean"
+
+	case "!!int":
+		return "an integer"
+
+	case "!!float":
+		return "a number"
+
+	default:
+		return "a string"
+	}
+}
+
+func (checker *fieldChecker) describeMapping() string {
+	switch checker.format {
+	case ConfigFormatJSON:
+		return "an object"
+
+	case ConfigFormatTOML:
+		return "a table"
+
+	default:
+		return "a mapping"
+	}
+}
+
+func joinPath(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// suggestField returns the name closest to an unknown
+// field, if one is close enough to likely be a typo.
+func suggestField(unknown string, names []string) string {
+	slices.Sort(names)
+
+	var (
+		best     string
+		bestDist = max(2, len(unknown)/3) + 1
+	)
+
+	for _, name := range names {
+		if dist := editDistance(strings.ToLower(unknown), name); dist < bestDist {
+			best, bestDist = name, dist
+		}
+	}
+
+	return best
+}
+
+// editDistance returns the Levenshtein distance
+// between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev = cur
+	}
+
+	return prev[len(b)]
+}
+
+// parseJSONNode parses data into the same tree of nodes
+// as yaml.v3 produces, so that JSON and YAML are checked
+// the same way.
+func parseJSONNode(data []byte) (*yaml.Node, error) {
+	parser := jsonNodeParser{data: data, dec: json.NewDecoder(bytes.NewReader(data))}
+	parser.dec.UseNumber()
+
+	node, err := parser.next()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line, column := parser.position(syntaxErr.Offset)
+		return nil, &DecodeError{Line: line, Column: column, Message: syntaxErr.Error()}
+	}
+
+	return node, err
+}
+
+type jsonNodeParser struct {
+	data []byte
+	dec  *json.Decoder
+}
+
+// position returns the line and column of
+// the byte at offset, both counted from 1.
+func (parser *jsonNodeParser) position(offset int64) (int, int) {
+	offset = min(offset, int64(len(parser.data)))
+	before := parser.data[:offset]
+
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+
+	return line, column
+}
+
+func (parser *jsonNodeParser) next() (*yaml.Node, error) {
+	// The decoder's offset is the end of the previous
+	// token, the next starts after any whitespace and
+	// separator
+	start := parser.dec.InputOffset()
+	for start < int64(len(parser.data)) && strings.IndexByte(" \t\r\n,:", parser.data[start]) >= 0 {
+		start++
+	}
+
+	token, err := parser.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	node := new(yaml.Node)
+	node.Line, node.Column = parser.position(start)
+
+	switch value := token.(type) {
+	case json.Delim:
+		node.Kind = yaml.MappingNode
+		if value == '[' {
+			node.Kind = yaml.SequenceNode
+		}
+
+		for parser.dec.More() {
+			child, err := parser.next()
+			if err != nil {
+				return nil, err
+			}
+
+			node.Content = append(node.Content, child)
+		}
+
+		// Consume the closing delimiter
+		if _, err = parser.dec.Token(); err != nil {
+			return nil, err
+		}
+
+		return node, nil
+
+	case string:
+		node.Tag, node.Value = "!!str", value
+
+	case json.Number:
+		node.Tag, node.Value = "!!float", value.String()
+		if _, err := strconv.ParseInt(value.String(), 10, 64); err == nil {
+			node.Tag = "!!int"
+		}
+
+	case bool:
+		node.Tag, node.Value = "!!bool", strconv.FormatBool(value)
+
+	case nil:
+		node.Tag = "!!null"
+	}
+
+	node.Kind = yaml.ScalarNode
+	return node, nil
+}
+
+// parseTOMLNode parses data into the same tree of nodes
+// as yaml.v3 produces, the TOML decoder doesn't expose the
+// position of values so only parse errors have one.
+func parseTOMLNode(data []byte) (*yaml.Node, error) {
+	var values map[string]any
+	if _, err := toml.Decode(string(data), &values); err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &DecodeError{Line: parseErr.Position.Line, Column: parseErr.Position.Col, Message: parseErr.Message}
+		}
+
+		return nil, err
+	}
+
+	return tomlValueNode(values), nil
+}
+
+func tomlValueNode(value any) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode}
+
+	switch value := value.(type) {
+	case map[string]any:
+		node.Kind = yaml.MappingNode
+
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+
+		slices.Sort(keys)
+		for _, key := range keys {
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+				tomlValueNode(value[key]),
+			)
+		}
+
+	case []map[string]any:
+		node.Kind = yaml.SequenceNode
+		for _, item := range value {
+			node.Content = append(node.Content, tomlValueNode(item))
+		}
+
+	case []any:
+		node.Kind = yaml.SequenceNode
+		for _, item := range value {
+			node.Content = append(node.Content, tomlValueNode(item))
+		}
+
+	case int64:
+		node.Tag = "!!int"
+
+	case float64:
+		node.Tag = "!!float"
+
+	case bool:
+		node.Tag = "!!bool"
+
+	default:
+		// Strings, and dates and times which
+		// are decoded into strings through JSON
+		node.Tag = "!!str"
+	}
+
+	return node
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// decodeString decodes data, written to a file
+// named name, as decodeConfigurationFile does.
+func decodeString(t *testing.T, name, data string) error {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+
+	_, err := decodeConfigurationFile(path, ConfigFormatAuto)
+	return err
+}
+
+func TestDecodeStrict(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+
+		// expected lists the errors expected, each must
+		// appear in the error returned on a line of its own
+		expected []string
+	}{
+		{
+			name: "yaml unknown field",
+			file: "config.yaml",
+			data: "config_version: 3\n" +
+				"defaults:\n" +
+				"  stapel: true\n" +
+				"packages:\n" +
+				"  - file: App.dmg\n",
+			expected: []string{"line 3, column 3: defaults: unknown field 'stapel', did you mean 'staple'?"},
+		},
+		{
+			name: "yaml unknown field without suggestion",
+			file: "config.yaml",
+			data: "config_version: 3\n" +
+				"packages:\n" +
+				"  - file: App.dmg\n" +
+				"    signing_identity: Developer ID\n",
+			expected: []string{"line 4, column 5: packages[0]: unknown field 'signing_identity'"},
+		},
+		{
+			name: "yaml key_issuer",
+			file: "config.yaml",
+			data: "config_version: 3\n" +
+				"defaults:\n" +
+				"  notary_auth:\n" +
+				"    key_id: ABC123\n" +
+				"    key_issuer: 00000000-0000-0000-0000-000000000000\n" +
+				"packages:\n" +
+				"  - file: App.dmg\n",
+			expected: []string{"line 5, column 5: defaults.notary_auth: unknown field 'key_issuer', did you mean 'key_issuer_id'?"},
+		},
+		{
+			name: "yaml type mismatches",
+			file: "config.yaml",
+			data: "config_version: 3\n" +
+				"upload:\n" +
+				"  part_size: big\n" +
+				"packages:\n" +
+				"  - file: App.dmg\n" +
+				"    staple: \"yes\"\n",
+			expected: []string{
+				"line 3, column 14: upload.part_size: expected an integer but found a string",
+				"line 6, column 13: packages[0].staple: expected a boolean but found a string",
+			},
+		},
+		{
+			name: "yaml inline package settings",
+			file: "config.yaml",
+			data: "config_version: 3\n" +
+				"packages:\n" +
+				"  - file: App.dmg\n" +
+				"    staple: true\n" +
+				"    timeout: 30m\n" +
+				"    archive:\n" +
+				"      reproducable: true\n",
+			expected: []string{"line 7, column 7: packages[0].archive: unknown field 'reproducable', did you mean 'reproducible'?"},
+		},
+		{
+			name: "yaml mapping for a list",
+			file: "config.yaml",
+			data: "config_version: 3\n" +
+				"packages:\n" +
+				"  file: App.dmg\n",
+			expected: []string{"line 3, column 3: packages: expected a list but found a mapping"},
+		},
+		{
+			name: "json unknown field",
+			file: "config.json",
+			data: "{\n" +
+				"  \"config_version\": 3,\n" +
+				"  \"defaults\": {\"stapel\": true},\n" +
+				"  \"packages\": [{\"file\": \"App.dmg\"}]\n" +
+				"}\n",
+			expected: []string{"line 3, column 16: defaults: unknown field 'stapel', did you mean 'staple'?"},
+		},
+		{
+			name: "json key_issuer",
+			file: "config.json",
+			data: "{\n" +
+				"  \"config_version\": 3,\n" +
+				"  \"defaults\": {\n" +
+				"    \"notary_auth\": {\"key_id\": \"ABC123\", \"key_issuer\": \"00000000-0000-0000-0000-000000000000\"}\n" +
+				"  },\n" +
+				"  \"packages\": [{\"file\": \"App.dmg\"}]\n" +
+				"}\n",
+			expected: []string{"line 4, column 41: defaults.notary_auth: unknown field 'key_issuer', did you mean 'key_issuer_id'?"},
+		},
+		{
+			name: "json type mismatches",
+			file: "config.json",
+			data: "{\n" +
+				"  \"config_version\": 3,\n" +
+				"  \"upload\": {\"part_size\": \"big\"},\n" +
+				"  \"packages\": [{\"file\": \"App.dmg\", \"staple\": \"yes\"}]\n" +
+				"}\n",
+			expected: []string{
+				"line 3, column 27: upload.part_size: expected an integer but found a string",
+				"line 4, column 46: packages[0].staple: expected a boolean but found a string",
+			},
+		},
+		{
+			name: "json inline package settings",
+			file: "config.json",
+			data: "{\n" +
+				"  \"config_version\": 3,\n" +
+				"  \"packages\": [\n" +
+				"    {\"file\": \"App.dmg\", \"staple\": true, \"auth_profil\": \"release\"}\n" +
+				"  ]\n" +
+				"}\n",
+			expected: []string{"line 4, column 41: packages[0]: unknown field 'auth_profil', did you mean 'auth_profile'?"},
+		},
+		{
+			name: "json syntax error",
+			file: "config.json",
+			data: "{\n" +
+				"  \"config_version\": 3,\n" +
+				"  \"packages\": [{\"file\": \"App.dmg\",}]\n" +
+				"}\n",
+			expected: []string{"line 3, column 35: invalid character ',' looking for beginning of value"},
+		},
+		{
+			name: "json syntax error at end of file",
+			file: "config.json",
+			data: "{\n" +
+				"  \"config_version\": 3,\n" +
+				"  \"packages\": [{\"file\": \"App.dmg\"}]\n",
+			expected: []string{"line 4, column 1: unexpected end of JSON input"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := decodeString(t, test.file, test.data)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			lines := strings.Split(err.Error(), "\n")
+			for _, expected := range test.expected {
+				found := false
+				for _, line := range lines {
+					found = found || strings.HasSuffix(line, expected)
+				}
+
+				if !found {
+					t.Errorf("expected error %q, got:\n%v", expected, err)
+				}
+			}
+
+			if len(lines) != len(test.expected) {
+				t.Errorf("expected %d errors, got %d:\n%v", len(test.expected), len(lines), err)
+			}
+		})
+	}
+}
+
+func TestDecodeStrictValid(t *testing.T) {
+	tests := []struct {
+		file, data string
+	}{
+		{
+			file: "config.yaml",
+			data: "config_version: 3\n" +
+				"upload:\n" +
+				"  part_size: 10485760\n" +
+				"defaults:\n" +
+				"  staple: yes\n" +
+				"packages:\n" +
+				"  - file: App.dmg\n" +
+				"    staple: false\n" +
+				"    timeout: 30m\n",
+		},
+		{
+			file: "config.json",
+			data: "{\n" +
+				"  \"config_version\": 3,\n" +
+				"  \"upload\": {\"part_size\": 10485760},\n" +
+				"  \"packages\": [{\"file\": \"App.dmg\", \"Staple\": false, \"timeout\": \"30m\"}]\n" +
+				"}\n",
+		},
+	}
+
+	for _, test := range tests {
+		if err := decodeString(t, test.file, test.data); err != nil {
+			t.Errorf("decode %s returned error: %v", test.file, err)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package configcmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/config"
+	"github.com/KatelynHaworth/notarization-helper/v2/internal/cmd/exitcode"
+	. "github.com/KatelynHaworth/notarization-helper/v2/internal/cmd/globals"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/worker"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ConfigCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Check or migrate the utility configuration",
+	}
+
+	validateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration for problems without contacting Apple",
+		Args:  cobra.NoArgs,
+		RunE:  runValidate,
+
+		Annotations: map[string]string{AnnotationSkipConfig: "true"},
+	}
+
+	migrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Rewrite a version 1 configuration, or the legacy flags, as a version 2 configuration",
+		Args:  cobra.NoArgs,
+		RunE:  runMigrate,
+
+		Annotations: map[string]string{AnnotationSkipConfig: "true"},
+	}
+
+	migrateOutput *string
+
+	legacyUsername *string
+	legacyPassword *string
+	legacyTeamId   *string
+	legacyStaple   *bool
+)
+
+func init() {
+	migrateOutput = migrateCmd.Flags().StringP("output", "o", "", "Specifies the file to write the migrated configuration to, as JSON if the file extension is .json or YAML otherwise, instead of stdout")
+	legacyUsername = migrateCmd.Flags().String("username", "", "Specifies the Apple Developer account username to migrate instead of a configuration file")
+	legacyPassword = migrateCmd.Flags().String("password", "", "Specifies the password for the Apple Developer Account to migrate instead of a configuration file")
+	legacyTeamId = migrateCmd.Flags().String("team-id", "", "Optionally specifies the Team ID associated with the Apple Developer account to migrate")
+	legacyStaple = migrateCmd.Flags().Bool("staple", false, "Optionally specifies that the migrated package should be stapled")
+
+	ConfigCmd.AddCommand(validateCmd, migrateCmd)
+}
+
+func runValidate(cmd *cobra.Command, _ []string) error {
+	file, _ := cmd.Flags().GetString("file")
+
+	overrides, err := config.EnvironmentOverrides()
+	if err != nil {
+		return fmt.Errorf("%w: %w", exitcode.ErrConfiguration, err)
+	}
+
+	if file = ConfigFile(file, cmd.Flags().Changed("file"), overrides); len(file) > 0 {
+		Logger.Info().Str("file", file).Msg("Validating utility configuration")
+	} else {
+		Logger.Info().Msg("No configuration file found, validating the environment alone")
+	}
+
+	cfg, err := config.ParseConfiguration(file, config.ConfigFormatAuto, overrides)
+
+	var problems []error
+	if err != nil {
+		problems = splitErrors(err)
+	} else {
+		problems = validateConfig(cmd.Context(), cfg)
+	}
+
+	for _, problem := range problems {
+		Logger.Error().Err(problem).Msg("Configuration problem")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: found %d problem(s) in the configuration", exitcode.ErrConfiguration, len(problems))
+	}
+
+	Logger.Info().Int("packages", len(cfg.Packages)).Msg("Configuration is valid")
+	return nil
+}
+
+// validateConfig checks the parts of the configuration
+// that are only otherwise checked when packages are
+// submitted, without contacting the Notary API.
+func validateConfig(ctx context.Context, cfg *config.ConfigurationV3) []error {
+	var problems []error
+	if _, err := cfg.Endpoints.Resolve(); err != nil {
+		problems = append(problems, fmt.Errorf("endpoints: %w", err))
+	}
+
+	if _, err := cfg.Network.ClientOptions(); err != nil {
+		problems = append(problems, fmt.Errorf("network: %w", err))
+	}
+
+	validated := make(map[*config.ConfigurationV2_NotaryAuth]bool)
+	for i := range cfg.Packages {
+		pkg := &cfg.Packages[i]
+		settings := cfg.Settings(pkg)
+
+		var mode config.ArchiveMode
+		if settings.Archive != nil {
+			mode = settings.Archive.Mode
+		}
+
+		if err := worker.ValidatePackage(pkg.File, mode); err != nil {
+			problems = append(problems, fmt.Errorf("package '%s': %w", pkg.File, err))
+		}
+
+		if auth := settings.NotaryAuth; !validated[auth] {
+			validated[auth] = true
+
+			if auth.HasRemoteSecrets() {
+				Logger.Warn().Str("file", pkg.File).Msg("Auth settings reference secrets fetched over the network, they are not resolved and the key is not checked")
+			}
+
+			if auth.HasCommandSecrets() {
+				Logger.Warn().Str("file", pkg.File).Msg("Auth settings reference secrets output by a command, which will be run, " +
+					"prefix a value with 'literal:' if it isn't a reference")
+			}
+
+			if err := auth.Validate(ctx); err != nil {
+				problems = append(problems, fmt.Errorf("package '%s': notary auth: %w", pkg.File, err))
+			}
+		}
+	}
+
+	return problems
+}
+
+// splitErrors returns the errors joined in
+// err, or err itself if it isn't joined.
+func splitErrors(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+
+	return []error{err}
+}
+
+// migratedConfiguration adds the version to a
+// ConfigurationV2 when it is written out.
+type migratedConfiguration struct {
+	ConfigVersion          int `json:"config_version" yaml:"config_version"`
+	config.ConfigurationV2 `yaml:",inline"`
+}
+
+func runMigrate(cmd *cobra.Command, _ []string) error {
+	file, _ := cmd.Flags().GetString("file")
+
+	var (
+		v1  *config.ConfigurationV1
+		err error
+	)
+
+	if len(*legacyUsername) != 0 && len(*legacyPassword) != 0 {
+		v1 = &config.ConfigurationV1{
+			Username: *legacyUsername,
+			Password: *legacyPassword,
+			TeamID:   *legacyTeamId,
+			Packages: []config.Package{{
+				File:   file,
+				Staple: *legacyStaple,
+			}},
+		}
+	} else if v1, err = config.LoadConfigurationV1FromFile(file, config.ConfigFormatAuto); err != nil {
+		return fmt.Errorf("%w: load version 1 config from file: %w", exitcode.ErrConfiguration, err)
+	}
+
+	// Secret references in the credentials are carried
+	// over as is by ToV2, so the output never contains
+	// a resolved secret
+	v2, err := v1.ToV2()
+	if err != nil {
+		return fmt.Errorf("%w: convert V1 config to V2: %w", exitcode.ErrConfiguration, err)
+	}
+
+	migrated := migratedConfiguration{ConfigVersion: 2, ConfigurationV2: *v2}
+	if len(*migrateOutput) == 0 {
+		return encodeConfig(os.Stdout, migrated, config.ConfigFormatYAML)
+	}
+
+	out, err := os.OpenFile(*migrateOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("create migrated config file: %w", err)
+	}
+
+	format := config.ConfigFormatYAML
+	if filepath.Ext(*migrateOutput) == ".json" {
+		format = config.ConfigFormatJSON
+	}
+
+	if err = errors.Join(encodeConfig(out, migrated, format), out.Close()); err != nil {
+		return err
+	}
+
+	Logger.Info().Str("outputFile", *migrateOutput).Msg("Wrote migrated configuration")
+	return nil
+}
+
+func encodeConfig(w io.Writer, cfg migratedConfiguration, format config.ConfigFormat) error {
+	if format == config.ConfigFormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		if err := enc.Encode(cfg); err != nil {
+			return fmt.Errorf("encode migrated config as JSON: %w", err)
+		}
+
+		return nil
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(cfg); err != nil {
+		return fmt.Errorf("encode migrated config as YAML: %w", err)
+	}
+
+	return enc.Close()
+}
@@ -0,0 +1,88 @@
+// Package exitcode maps the errors returned by the
+// utility's commands to the process exit code, so that
+// pipelines can distinguish why notarization failed.
+package exitcode
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/worker"
+)
+
+const (
+	OK = 0
+
+	// Error is used for any error
+	// without a more specific code.
+	Error = 1
+
+	// Configuration is used when the configuration
+	// couldn't be loaded or a package in it couldn't
+	// be prepared for submission.
+	Configuration = 2
+
+	// SubmissionFailed is used when the Notary
+	// marked a submission as invalid or rejected it.
+	SubmissionFailed = 3
+
+	// Policy is used when an accepted package has
+	// issues in its notarization log that violate
+	// the configured policy.
+	Policy = 7
+
+	// Transport is used when communication with the
+	// Notary API, S3, or CloudKit failed.
+	Transport = 4
+
+	// Staple is used when a package was accepted but
+	// the ticket couldn't be stapled to it.
+	Staple = 5
+
+	// Timeout is used when notarization didn't
+	// complete within the allowed time.
+	Timeout = 6
+
+	// Interrupted is used when the utility
+	// was interrupted by a signal.
+	Interrupted = 130
+)
+
+// ErrConfiguration wraps errors caused by
+// the configuration of the utility.
+var ErrConfiguration = errors.New("configuration error")
+
+// For returns the exit code for err, when err holds
+// errors for several packages the code is chosen in
+// the order: configuration, timeout, interrupted,
+// transport, submission failed, policy, staple.
+func For(err error) int {
+	switch {
+	case err == nil:
+		return OK
+
+	case errors.Is(err, ErrConfiguration):
+		return Configuration
+
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout
+
+	case errors.Is(err, context.Canceled):
+		return Interrupted
+
+	case errors.Is(err, worker.ErrTransport):
+		return Transport
+
+	case errors.Is(err, worker.ErrSubmissionInvalid), errors.Is(err, worker.ErrSubmissionRejected):
+		return SubmissionFailed
+
+	case errors.Is(err, worker.ErrPolicyViolation):
+		return Policy
+
+	case errors.Is(err, worker.ErrStaple):
+		return Staple
+
+	default:
+		return Error
+	}
+}
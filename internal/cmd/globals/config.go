@@ -1,7 +1,33 @@
 package globals
 
-import "github.com/KatelynHaworth/notarization-helper/v2/config"
+import (
+	"os"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/config"
+)
+
+// AnnotationSkipConfig is set to "true" in the
+// annotations of commands that handle the utility
+// configuration themselves, so that it isn't loaded
+// before they run.
+const AnnotationSkipConfig = "notarization-helper/skip-config"
 
 var (
-	Config *config.ConfigurationV2
+	Config *config.ConfigurationV3
 )
+
+// ConfigFile returns the configuration file to load, file
+// unless it is the default (explicit is false), doesn't
+// exist, and the overrides can be used without a file, in
+// which case it returns an empty string.
+func ConfigFile(file string, explicit bool, overrides config.Overrides) string {
+	if explicit || overrides.IsEmpty() {
+		return file
+	}
+
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return ""
+	}
+
+	return file
+}
@@ -1,13 +1,89 @@
 package globals
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
 )
 
+const (
+	LogEncodingConsole = "console"
+	LogEncodingJSON    = "json"
+)
+
 var (
-	Logger = zerolog.New(zerolog.NewConsoleWriter(func(w *zerolog.ConsoleWriter) {
+	Logger = zerolog.New(logOutput).With().Timestamp().Logger()
+
+	// logOutput is the writer Logger writes to and
+	// logToFile is set if it writes to a file given
+	// by --log-file rather than stdout.
+	logOutput io.Writer = zerolog.NewConsoleWriter(func(w *zerolog.ConsoleWriter) {
 		w.TimeFormat = time.RFC3339
-	})).With().Timestamp().Logger()
+	})
+	logToFile bool
 )
+
+// ConfigureLogger replaces Logger with one that writes
+// in the specified encoding, either to stdout or, if path
+// isn't empty, appended to the file at path, and sets
+// the minimum level of log messages written.
+//
+// The returned function flushes and closes the log file,
+// it must be called once nothing more will be logged.
+func ConfigureLogger(encoding, level, path string) (func() error, error) {
+	lvl, err := zerolog.ParseLevel(strings.ToLower(level))
+	if err != nil {
+		return nil, fmt.Errorf("parse log level: %w", err)
+	} else if encoding != LogEncodingConsole && encoding != LogEncodingJSON {
+		return nil, fmt.Errorf("unsupported log encoding '%s', must be console or json", encoding)
+	}
+
+	var out io.Writer = os.Stdout
+	closer := func() error { return nil }
+
+	if len(path) > 0 {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("open log file: %w", err)
+		}
+
+		out = file
+		closer = func() error {
+			return errors.Join(file.Sync(), file.Close())
+		}
+	}
+
+	if encoding == LogEncodingConsole {
+		out = zerolog.NewConsoleWriter(func(w *zerolog.ConsoleWriter) {
+			w.Out = out
+			w.TimeFormat = time.RFC3339
+			w.NoColor = len(path) > 0
+		})
+	} else {
+		zerolog.TimeFieldFormat = time.RFC3339Nano
+	}
+
+	zerolog.SetGlobalLevel(lvl)
+	Logger = zerolog.New(out).With().Timestamp().Logger()
+	logOutput, logToFile = out, len(path) > 0
+
+	return closer, nil
+}
+
+// ShareLogOutput replaces Logger with one whose output
+// is passed through share, so that something else drawn
+// on the terminal, such as progress bars, can coordinate
+// with the log messages. It has no effect when the logs
+// are written to a file.
+func ShareLogOutput(share func(out io.Writer) io.Writer) {
+	if logToFile {
+		return
+	}
+
+	Logger = Logger.Output(share(logOutput))
+}
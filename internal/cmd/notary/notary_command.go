@@ -1,59 +1,290 @@
 package notary
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/config"
+	"github.com/KatelynHaworth/notarization-helper/v2/internal/cmd/exitcode"
 	. "github.com/KatelynHaworth/notarization-helper/v2/internal/cmd/globals"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/annotate"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/logrender"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/progress"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/report"
 	"github.com/KatelynHaworth/notarization-helper/v2/notarize/worker"
+	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
 )
 
 var (
 	NotarizeCmd = &cobra.Command{
-		Use:   "notarize",
+		Use:   "notarize [files...]",
 		Short: "Upload files to Apple for notarization",
-		RunE:  run,
+		Long: "Upload files to Apple for notarization, the files given as arguments replace the packages in the configuration file.\n\n" +
+			"The App Store Connect key can be given by the " + config.EnvNotaryKeyId + ", " + config.EnvNotaryKey + " (PEM or base64 encoded), and " +
+			config.EnvNotaryIssuer + " environment variables, which take precedence over the defaults in the configuration file, " +
+			"so that no configuration file is needed.",
+		Args: cobra.ArbitraryArgs,
+		RunE: run,
 	}
+
+	progressMode *string
+	reportPath   *string
+	timeout      *time.Duration
+	logFormat    *string
+
+	annotateMode      *string
+	codeQualityReport *string
 )
 
+func init() {
+	_ = NotarizeCmd.Flags().Bool("staple", false, "Specifies that the notarization ticket should be stapled to every package on completion, overriding the configuration")
+	progressMode = NotarizeCmd.Flags().String("progress", string(progress.ModeAuto), "Specifies how upload and polling progress is reported on stderr: auto, bar, json, or none")
+	timeout = NotarizeCmd.Flags().Duration("timeout", 0, "Specifies the maximum time allowed for all packages to be notarized (e.g. 1h), no limit if zero")
+	logFormat = NotarizeCmd.Flags().String("log-format", string(logrender.FormatJSON), "Specifies the format of the notarization log saved next to each package: json, text, markdown, or html")
+
+	annotateMode = NotarizeCmd.Flags().String("annotate", "none", "Specifies the CI system to report notarization issues and failures to as annotations: github, gitlab, or none")
+	codeQualityReport = NotarizeCmd.Flags().String("code-quality-report", "gl-code-quality-report.json", "Specifies the file the GitLab Code Quality report is written to when annotating for gitlab")
+	reportPath = NotarizeCmd.Flags().String("report", "", "Specifies a file to write a report of the run to, as JUnit XML if the file extension is .xml or JSON otherwise")
+}
+
 func run(cmd *cobra.Command, _ []string) error {
-	group, gCtx := errgroup.WithContext(cmd.Context())
+	reporter, err := progress.New(progress.Mode(*progressMode), os.Stderr)
+	if err != nil {
+		return fmt.Errorf("%w: create progress reporter: %w", exitcode.ErrConfiguration, err)
+	}
+	defer reporter.Close()
+
+	// Progress bars are redrawn in place, so log messages
+	// written to the same terminal must go through the
+	// reporter to avoid the two garbling each other
+	ShareLogOutput(func(out io.Writer) io.Writer {
+		return progress.LogWriter(reporter, out)
+	})
+
+	notaryLogFormat, err := logrender.ParseFormat(*logFormat)
+	if err != nil {
+		return fmt.Errorf("%w: %w", exitcode.ErrConfiguration, err)
+	}
+
+	switch *annotateMode {
+	case "none", "github", "gitlab":
+	default:
+		return fmt.Errorf("%w: unsupported annotation mode '%s', must be github, gitlab, or none", exitcode.ErrConfiguration, *annotateMode)
+	}
+
+	endpoints, err := Config.Endpoints.Resolve()
+	if err != nil {
+		return fmt.Errorf("%w: resolve service endpoints: %w", exitcode.ErrConfiguration, err)
+	}
+
+	networkOpts, err := Config.Network.ClientOptions()
+	if err != nil {
+		return fmt.Errorf("%w: apply network configuration: %w", exitcode.ErrConfiguration, err)
+	}
+
+	apiTrace, _ := strconv.ParseBool(os.Getenv("API_TRACE"))
+	client, err := api.NewClient(append([]api.ClientOption{
+		api.WithEndpoints(endpoints),
+		api.WithUserAgent(fmt.Sprintf("notarization-helper/%s", cmd.Root().Version)),
+		api.WithLogger(Logger),
+		api.WithDebug(apiTrace),
+	}, networkOpts...)...)
+	if err != nil {
+		return fmt.Errorf("%w: create notary API client: %w", exitcode.ErrConfiguration, err)
+	}
+
+	ctx := cmd.Context()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	// Workers are run independently so that a
+	// failure of one package doesn't cancel the
+	// notarization of the others
+	var group errgroup.Group
+
+	started := time.Now()
 	wkrs := make([]*worker.Worker, len(Config.GetPackages()))
+	spawnErrs := make([]error, len(Config.GetPackages()))
+	wkrErrs := make([]error, len(Config.GetPackages()))
 
 	for i, p := range Config.GetPackages() {
-		wLogger := Logger.With().Str("file", p.File).Logger()
-		wLogger.Info().Str("file", p.File).Msg("Spawning notarization worker")
+		settings := Config.Settings(&Config.Packages[i])
+
+		wLoggerCtx := Logger.With().Str("file", p.File)
+		if len(settings.AuthProfile) > 0 {
+			wLoggerCtx = wLoggerCtx.Str("authProfile", settings.AuthProfile)
+		}
 
-		wkr, err := worker.NewWorker(Config.NotaryAuth, p, wLogger)
+		wLogger := wLoggerCtx.Logger()
+		wLogger.Info().Msg("Spawning notarization worker")
+
+		wkr, pkgTimeout, err := spawnWorker(client, reporter, p, settings, wLogger)
 		if err != nil {
 			wLogger.Error().Err(err).Msg("Failed to spawn notarization worker")
+			spawnErrs[i] = fmt.Errorf("%w: spawn worker for '%s': %w", exitcode.ErrConfiguration, p.File, err)
 			continue
 		}
 
+		defer wkr.Close()
+
 		wkrs[i] = wkr
 		group.Go(func() error {
-			return wkr.UploadAndWait(gCtx)
+			wCtx := ctx
+			if pkgTimeout > 0 {
+				var cancel context.CancelFunc
+				wCtx, cancel = context.WithTimeout(wCtx, pkgTimeout)
+				defer cancel()
+			}
+
+			if err := wkr.UploadAndWait(wCtx); err != nil {
+				wkrErrs[i] = fmt.Errorf("notarize '%s': %w", p.File, err)
+			}
+
+			return nil
 		})
 	}
 
-	if err := group.Wait(); err != nil {
+	_ = group.Wait()
+	_ = reporter.Close()
+
+	err = errors.Join(append(spawnErrs, wkrErrs...)...)
+
+	if err != nil {
 		Logger.Error().Err(err).Msg("One or more notarization workers failed")
 	} else {
 		Logger.Info().Msg("Notarization completed, saving log files")
 	}
 
 	for _, wkr := range wkrs {
+		if wkr == nil {
+			continue
+		}
+
 		wLogger := wkr.Logger()
 		if wkr.GetNotarizationLog() == nil {
 			wLogger.Error().Msg("No notarization log file available to save")
 			continue
 		}
 
-		if path, err := wkr.SaveNotarizationLog(); err != nil {
+		if path, err := wkr.SaveNotarizationLog(notaryLogFormat); err != nil {
 			wLogger.Error().Err(err).Msg("Encountered error while saving notarization log")
 		} else {
 			wLogger.Info().Str("log-file", path).Msg("Saved notarization log")
 		}
 	}
 
+	results := packageResults(wkrs, spawnErrs)
+	if len(*reportPath) > 0 {
+		if reportErr := writeReport(*reportPath, started, results); reportErr != nil {
+			Logger.Error().Err(reportErr).Msg("Failed to write report")
+		} else {
+			Logger.Info().Str("reportFile", *reportPath).Msg("Saved run report")
+		}
+	}
+
+	if *annotateMode != "none" {
+		if annotateErr := writeAnnotations(*annotateMode, results, wkrs); annotateErr != nil {
+			Logger.Error().Err(annotateErr).Msg("Failed to write CI annotations")
+		}
+	}
+
+	return err
+}
+
+// spawnWorker creates the worker for a package using the
+// settings for that package, returning it along with the
+// time allowed for the package to be notarized.
+func spawnWorker(client *api.Client, reporter progress.Reporter, p config.Package, settings config.ConfigurationV3_Settings, logger zerolog.Logger) (*worker.Worker, time.Duration, error) {
+	pkgTimeout, err := settings.GetTimeout()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	issuePolicy, err := settings.Policy.Resolve()
+	if err != nil {
+		return nil, 0, fmt.Errorf("resolve policy: %w", err)
+	}
+
+	settings.NotaryAuth.SetApiClient(client)
+
+	wkr, err := worker.NewWorker(client, settings.NotaryAuth, p, logger,
+		worker.WithUploadConfig(Config.Upload),
+		worker.WithArchiveConfig(settings.Archive),
+		worker.WithPolicy(issuePolicy),
+		worker.WithLogOutput(settings.LogOutput),
+		worker.WithProgress(reporter),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return wkr, pkgTimeout, nil
+}
+
+// packageResults returns the result of each package in
+// the configuration, including those whose worker
+// failed to spawn.
+func packageResults(wkrs []*worker.Worker, spawnErrs []error) []report.Package {
+	results := make([]report.Package, 0, len(wkrs))
+	for i, p := range Config.GetPackages() {
+		if wkrs[i] != nil {
+			results = append(results, wkrs[i].Result())
+			continue
+		}
+
+		results = append(results, report.Package{
+			File:   p.File,
+			Status: "NotSubmitted",
+			Errors: []string{spawnErrs[i].Error()},
+		})
+	}
+
+	return results
+}
+
+func writeReport(path string, started time.Time, results []report.Package) error {
+	runReport := &report.Report{
+		Started:  started,
+		Finished: time.Now(),
+		Packages: results,
+	}
+
+	return runReport.WriteFile(path)
+}
+
+func writeAnnotations(mode string, results []report.Package, wkrs []*worker.Worker) error {
+	var annotations []annotate.Annotation
+	for i, result := range results {
+		var log *api.NotarizationLog
+		if wkrs[i] != nil {
+			log = wkrs[i].GetNotarizationLog()
+		}
+
+		annotations = append(annotations, annotate.ForPackage(result, log)...)
+	}
+
+	switch mode {
+	case "github":
+		return annotate.WriteGitHub(os.Stdout, annotations)
+
+	case "gitlab":
+		if err := annotate.WriteGitLabFile(*codeQualityReport, annotations); err != nil {
+			return err
+		}
+
+		Logger.Info().Str("reportFile", *codeQualityReport).Msg("Saved code quality report")
+	}
+
 	return nil
 }
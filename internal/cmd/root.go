@@ -5,10 +5,11 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"runtime/debug"
 
 	"github.com/KatelynHaworth/notarization-helper/v2/config"
+	"github.com/KatelynHaworth/notarization-helper/v2/internal/cmd/configcmd"
+	"github.com/KatelynHaworth/notarization-helper/v2/internal/cmd/exitcode"
 	. "github.com/KatelynHaworth/notarization-helper/v2/internal/cmd/globals"
 	"github.com/KatelynHaworth/notarization-helper/v2/internal/cmd/notary"
 	"github.com/rs/zerolog"
@@ -17,20 +18,34 @@ import (
 
 var (
 	rootCmd = cobra.Command{
-		Use:               "notarization-helper",
-		Version:           "devel",
-		Short:             "Flexible, simple, cross-platform macOS code signing (soon™️) and notarizing",
-		PersistentPreRunE: preRun,
-		RunE:              run,
+		Use:                "notarization-helper",
+		Version:            "devel",
+		Short:              "Flexible, simple, cross-platform macOS code signing (soon™️) and notarizing",
+		PersistentPreRunE:  preRun,
+		PersistentPostRunE: postRun,
+		RunE:               run,
+
+		// Errors are logged by Execute, which
+		// also maps them to the exit code
+		SilenceErrors: true,
+		SilenceUsage:  true,
 	}
 
 	verbose    *bool
 	targetFile *string
 
+	logEncoding *string
+	logLevel    *string
+	logFile     *string
+
 	legacyUsername *string
 	legacyPassword *string
 	legacyTeamId   *string
 	legacyStaple   *bool
+
+	// closeLogger closes the log file opened
+	// by ConfigureLogger, if there is one.
+	closeLogger = func() error { return nil }
 )
 
 func init() {
@@ -39,7 +54,10 @@ func init() {
 	}
 
 	verbose = rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enables logging of debug level logs by the utility")
-	targetFile = rootCmd.PersistentFlags().StringP("file", "f", "notarization.yaml", "Specifies either the file to process or utility configuration (if the file extension is .json, .yaml, or .yml)")
+	logEncoding = rootCmd.PersistentFlags().String("log-encoding", LogEncodingConsole, "Specifies the encoding of the logs written by the utility: console or json")
+	logLevel = rootCmd.PersistentFlags().String("log-level", "info", "Specifies the minimum level of logs written by the utility: trace, debug, info, warn, or error")
+	logFile = rootCmd.PersistentFlags().String("log-file", "", "Specifies a file to append the logs written by the utility to instead of stdout")
+	targetFile = rootCmd.PersistentFlags().StringP("file", "f", "notarization.yaml", "Specifies either the file to process or utility configuration (JSON, YAML, or TOML, or - to read it from stdin)")
 
 	legacyUsername = rootCmd.Flags().String("username", "", "(Legacy) Specifies the Apple Developer account username for notarization")
 	legacyPassword = rootCmd.Flags().String("password", "", "(Legacy) Specifies the password for the Apple Developer Account")
@@ -48,15 +66,27 @@ func init() {
 	legacyStaple = rootCmd.Flags().Bool("staple", false, "(Legacy) Optionally specifies that the notarization ticket should be staple to the package on completion (for supported file types)")
 
 	rootCmd.AddCommand(notary.NotarizeCmd)
+	rootCmd.AddCommand(configcmd.ConfigCmd)
 }
 
-func preRun(_ *cobra.Command, _ []string) error {
-	if *verbose {
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+func preRun(cmd *cobra.Command, args []string) error {
+	level := *logLevel
+	if *verbose && !cmd.Flags().Changed("log-level") {
+		level = zerolog.DebugLevel.String()
+	}
+
+	closer, err := ConfigureLogger(*logEncoding, level, *logFile)
+	if err != nil {
+		return fmt.Errorf("%w: configure logging: %w", exitcode.ErrConfiguration, err)
+	}
+
+	closeLogger = closer
+
+	if cmd.Annotations[AnnotationSkipConfig] == "true" {
+		return nil
 	}
 
 	Logger.Info().Msg("Loading utility configuration")
-	var err error
 
 	if len(*legacyUsername) != 0 && len(*legacyPassword) != 0 {
 		Logger.Warn().Msg("Detected usage of legacy config flags, using legacy configuration mode")
@@ -71,25 +101,78 @@ func preRun(_ *cobra.Command, _ []string) error {
 			}},
 		}
 
-		Config, err = legacyCfg.ToV2()
+		Config, err = legacyCfg.ToV3()
 		if err != nil {
-			return fmt.Errorf("convert V1 config to V2: %w", err)
+			return fmt.Errorf("%w: convert V1 config to V3: %w", exitcode.ErrConfiguration, err)
 		}
 	} else {
-		format := config.ConfigFormatJSON
-		if ext := filepath.Ext(*targetFile); ext == ".yaml" || ext == ".yml" {
-			format = config.ConfigFormatYAML
+		overrides, err := config.EnvironmentOverrides()
+		if err != nil {
+			return fmt.Errorf("%w: %w", exitcode.ErrConfiguration, err)
+		}
+
+		overrides.Files = args
+		if cmd.Flags().Changed("staple") {
+			staple, _ := cmd.Flags().GetBool("staple")
+			overrides.Staple = &staple
 		}
 
-		Config, err = config.LoadConfigurationFromFile(*targetFile, format)
+		srcFile := ConfigFile(*targetFile, cmd.Flags().Changed("file"), overrides)
+		if len(srcFile) == 0 {
+			Logger.Info().Msg("No configuration file found, using the environment and arguments alone")
+		}
+
+		Config, err = config.ParseConfiguration(srcFile, config.ConfigFormatAuto, overrides)
 		if err != nil {
-			return fmt.Errorf("load config from file: %w", err)
+			return fmt.Errorf("%w: load config: %w", exitcode.ErrConfiguration, err)
+		}
+
+		warnCommandSecrets(Config)
+		if err = Config.ResolveSecrets(cmd.Context()); err != nil {
+			return fmt.Errorf("%w: load config: %w", exitcode.ErrConfiguration, err)
 		}
 	}
 
 	return nil
 }
 
+// warnCommandSecrets warns about each set of auth
+// settings that reference a secret output by a
+// command, as a value meant literally that starts
+// with "exec:" would otherwise be run silently.
+func warnCommandSecrets(cfg *config.ConfigurationV3) {
+	warned := make(map[*config.ConfigurationV2_NotaryAuth]bool)
+	for i := range cfg.Packages {
+		auth := cfg.Settings(&cfg.Packages[i]).NotaryAuth
+		if auth == nil || warned[auth] || !auth.HasCommandSecrets() {
+			continue
+		}
+
+		warned[auth] = true
+		Logger.Warn().Str("file", cfg.Packages[i].File).Msg("Auth settings reference secrets output by a command, which will be run, " +
+			"prefix a value with 'literal:' if it isn't a reference")
+	}
+}
+
+// postRun closes the log file, it isn't called if the
+// command fails so Execute closes it in that case.
+func postRun(_ *cobra.Command, _ []string) error {
+	return flushLogger()
+}
+
+// flushLogger closes the log file, if there is one,
+// and is safe to call more than once.
+func flushLogger() error {
+	closer := closeLogger
+	closeLogger = func() error { return nil }
+
+	if err := closer(); err != nil {
+		return fmt.Errorf("close log file: %w", err)
+	}
+
+	return nil
+}
+
 func run(cmd *cobra.Command, args []string) error {
 	Logger.Warn().Msg("No sub-command supplied, operating in legacy mode and defaulting to the `notarize` sub-command")
 
@@ -101,6 +184,10 @@ func Execute() {
 	defer stop()
 
 	if err := rootCmd.ExecuteContext(ctx); err != nil {
-		Logger.Fatal().Err(err).Msg("Utility encountered a fatal error")
+		Logger.Error().Err(err).Msg("Utility encountered a fatal error")
+		_ = flushLogger()
+
+		stop()
+		os.Exit(exitcode.For(err))
 	}
 }
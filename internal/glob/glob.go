@@ -0,0 +1,144 @@
+// Package glob matches and expands glob patterns whose
+// elements are separated by "/", where an element of "**"
+// matches zero or more elements of a path.
+package glob
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// HasMeta reports if pattern contains any of
+// the characters special to path.Match.
+func HasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// Validate reports an error if pattern
+// isn't a valid glob.
+func Validate(pattern string) error {
+	for _, elem := range strings.Split(pattern, "/") {
+		if _, err := path.Match(elem, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Match reports if name matches pattern, both
+// separated by "/". Each element of pattern is
+// matched using path.Match, except "**" which
+// matches zero or more elements of name.
+func Match(pattern, name string) bool {
+	return matchElems(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchElems(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(name); skip++ {
+				if matchElems(pattern[1:], name[skip:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// Expand returns the files and directories matching pattern,
+// in lexical order. The walk doesn't descend into directories
+// that match, so a bundle such as an .app is returned as a
+// single path rather than also matching its contents.
+//
+// If a file or directory exists at pattern itself it is
+// returned as is, so that names containing characters
+// special to path.Match, such as "App [beta].dmg", can
+// still be given literally.
+func Expand(pattern string) ([]string, error) {
+	if _, err := os.Stat(pattern); err == nil {
+		return []string{filepath.Clean(pattern)}, nil
+	}
+
+	pattern = path.Clean(filepath.ToSlash(pattern))
+	if err := Validate(pattern); err != nil {
+		return nil, err
+	}
+
+	// Only the part of the tree below the
+	// elements of the pattern that don't
+	// contain a wildcard needs to be walked
+	elems := strings.Split(pattern, "/")
+	static := 0
+	for static < len(elems)-1 && !HasMeta(elems[static]) {
+		static++
+	}
+
+	root := strings.Join(elems[:static], "/")
+	switch {
+	case len(root) == 0 && strings.HasPrefix(pattern, "/"):
+		root = "/"
+
+	case len(root) == 0:
+		root = "."
+	}
+
+	// Without "**" a path can only match if it has
+	// as many elements as the pattern, so the walk
+	// needn't descend into directories that can't
+	// lead to a match, which may be slow to walk
+	// or unreadable
+	bounded := !slices.Contains(elems, "**")
+
+	var matches []string
+	err := filepath.WalkDir(filepath.FromSlash(root), func(current string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && current == filepath.FromSlash(root) {
+				return fs.SkipAll
+			}
+
+			return err
+		}
+
+		name := filepath.ToSlash(current)
+		if name == root {
+			return nil
+		}
+
+		if Match(pattern, name) {
+			matches = append(matches, filepath.FromSlash(name))
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		if nameElems := strings.Split(name, "/"); bounded && entry.IsDir() &&
+			(len(nameElems) >= len(elems) || !matchElems(elems[:len(nameElems)], nameElems)) {
+			return fs.SkipDir
+		}
+
+		return nil
+	})
+
+	return matches, err
+}
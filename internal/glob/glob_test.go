@@ -0,0 +1,130 @@
+package glob
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		matches       bool
+	}{
+		{"dist/*.pkg", "dist/app.pkg", true},
+		{"dist/*.pkg", "dist/sub/app.pkg", false},
+		{"dist/**/*.pkg", "dist/app.pkg", true},
+		{"dist/**/*.pkg", "dist/a/b/app.pkg", true},
+		{"dist/**", "dist", true},
+		{"**/*.app", "build/Release/Example.app", true},
+		{"App [beta].dmg", "App [beta].dmg", false},
+		{`App \[beta\].dmg`, "App [beta].dmg", true},
+	}
+
+	for _, test := range tests {
+		if matches := Match(test.pattern, test.name); matches != test.matches {
+			t.Errorf("Match(%q, %q) = %v, expected %v", test.pattern, test.name, matches, test.matches)
+		}
+	}
+}
+
+// createTree creates the files, relative to a new
+// temporary directory that becomes the working
+// directory for the rest of the test.
+func createTree(t *testing.T, files ...string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, file := range files {
+		file = filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("create directory: %v", err)
+		} else if err = os.WriteFile(file, nil, 0644); err != nil {
+			t.Fatalf("create file: %v", err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("get working directory: %v", err)
+	} else if err = os.Chdir(dir); err != nil {
+		t.Fatalf("change working directory: %v", err)
+	}
+
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+}
+
+func TestExpand(t *testing.T) {
+	createTree(t,
+		"dist/a.pkg",
+		"dist/b.pkg",
+		"dist/sub/c.pkg",
+		"dist/Example.app/Contents/Info.plist",
+		"App [beta].dmg",
+		"App b.dmg",
+	)
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"dist/*.pkg", []string{"dist/a.pkg", "dist/b.pkg"}},
+		{"dist/**/*.pkg", []string{"dist/a.pkg", "dist/b.pkg", "dist/sub/c.pkg"}},
+		{"./dist/*.app", []string{"dist/Example.app"}},
+		{"**/*.plist", []string{"dist/Example.app/Contents/Info.plist"}},
+		{"missing/*.pkg", nil},
+
+		// A file that exists is returned as is, even
+		// though "[beta]" would otherwise be treated
+		// as a character class
+		{"App [beta].dmg", []string{"App [beta].dmg"}},
+		{"App [b].dmg", []string{"App b.dmg"}},
+	}
+
+	for _, test := range tests {
+		matches, err := Expand(test.pattern)
+		if err != nil {
+			t.Errorf("Expand(%q) returned error: %v", test.pattern, err)
+			continue
+		}
+
+		expected := make([]string, 0, len(test.expected))
+		for _, file := range test.expected {
+			expected = append(expected, filepath.FromSlash(file))
+		}
+
+		if !slices.Equal(matches, expected) {
+			t.Errorf("Expand(%q) = %q, expected %q", test.pattern, matches, expected)
+		}
+	}
+}
+
+func TestExpandSkipsDeeperDirectories(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("unreadable directories can be read by root")
+	}
+
+	createTree(t, "dist/a.pkg", "dist/private/secret/b.pkg", "other/c.pkg")
+
+	// Neither directory can lead to a match of the
+	// pattern, so must not be read by the walk
+	for _, dir := range []string{"dist/private", "other"} {
+		if err := os.Chmod(dir, 0); err != nil {
+			t.Fatalf("make '%s' unreadable: %v", dir, err)
+		}
+
+		t.Cleanup(func() { _ = os.Chmod(dir, 0755) })
+	}
+
+	matches, err := Expand("dist/*.pkg")
+	if err != nil {
+		t.Fatalf("Expand() returned error: %v", err)
+	} else if expected := []string{filepath.FromSlash("dist/a.pkg")}; !slices.Equal(matches, expected) {
+		t.Errorf("Expand() = %q, expected %q", matches, expected)
+	}
+
+	if _, err = Expand("*/*.pkg"); err == nil {
+		t.Error("expected an error for an unreadable directory the pattern could match in")
+	}
+}
@@ -0,0 +1,150 @@
+// Package annotate converts notarization issues and
+// failures into annotations understood by CI systems,
+// so that they are shown alongside the change being
+// built rather than buried in the job log.
+package annotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/report"
+)
+
+// Severity of an annotation.
+type Severity string
+
+const (
+	SeverityFailure Severity = "failure"
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNotice  Severity = "notice"
+)
+
+// Annotation is a single message
+// attached to a file.
+type Annotation struct {
+	Severity Severity
+	File     string
+	Title    string
+	Message  string
+}
+
+// ForPackage returns the annotations for a notarized package,
+// one for each error that occurred and one for each issue in
+// its notarization log, which may be nil.
+func ForPackage(result report.Package, log *api.NotarizationLog) []Annotation {
+	var annotations []Annotation
+	for _, err := range result.Errors {
+		annotations = append(annotations, Annotation{
+			Severity: SeverityFailure,
+			File:     workspacePath(result.File),
+			Title:    "Notarization failed",
+			Message:  err,
+		})
+	}
+
+	if log == nil {
+		return annotations
+	}
+
+	for _, issue := range log.Issues {
+		var message strings.Builder
+		message.WriteString(issue.Path)
+		if len(issue.Architecture) > 0 {
+			fmt.Fprintf(&message, " (%s)", issue.Architecture)
+		}
+
+		fmt.Fprintf(&message, ": %s", issue.Message)
+		if len(issue.DocUrl) > 0 {
+			fmt.Fprintf(&message, "\nSee %s", issue.DocUrl)
+		}
+
+		title := fmt.Sprintf("Notarization %s", orDefault(issue.Severity, "issue"))
+		if len(issue.Code) > 0 {
+			title = fmt.Sprintf("%s %s", title, issue.Code)
+		}
+
+		annotations = append(annotations, Annotation{
+			Severity: issueSeverity(issue.Severity),
+			File:     workspacePath(SourcePath(result.File, log.ArchiveFilename, issue.Path)),
+			Title:    title,
+			Message:  message.String(),
+		})
+	}
+
+	return annotations
+}
+
+func issueSeverity(severity string) Severity {
+	switch strings.ToLower(severity) {
+	case "error":
+		return SeverityError
+
+	case "warning":
+		return SeverityWarning
+
+	default:
+		return SeverityNotice
+	}
+}
+
+// SourcePath maps the path of an issue, which starts with
+// the name of the archive submitted to the Notary, back to
+// the file on disk it was reported for. If the issue is
+// within a package that was archived before submission and
+// the file still exists it is returned, otherwise the path
+// of the package itself is returned.
+func SourcePath(packageFile, archiveName, issuePath string) string {
+	inner, found := strings.CutPrefix(issuePath, archiveName+"/")
+	if !found || len(archiveName) == 0 {
+		return packageFile
+	}
+
+	// Packages archived by the worker keep the package
+	// itself as the top level of the archive, the
+	// same as `ditto -c -k --keepParent`
+	if first, _, _ := strings.Cut(inner, "/"); first != filepath.Base(packageFile) {
+		return packageFile
+	}
+
+	candidate := filepath.Join(filepath.Dir(packageFile), filepath.FromSlash(inner))
+	if _, err := os.Lstat(candidate); err != nil {
+		return packageFile
+	}
+
+	return candidate
+}
+
+// workspacePath returns path relative to the current
+// directory, which CI systems expect file paths in
+// annotations to be, if it is within it.
+func workspacePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+func orDefault(value, fallback string) string {
+	if len(value) == 0 {
+		return fallback
+	}
+
+	return value
+}
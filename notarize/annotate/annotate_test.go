@@ -0,0 +1,215 @@
+package annotate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/report"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares output with the golden file
+// testdata/name, or replaces it when -update is set.
+func checkGolden(t *testing.T, name string, output []byte) {
+	t.Helper()
+
+	golden := filepath.Join(testdata, name)
+	if *update {
+		if err := os.WriteFile(golden, output, 0644); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+	}
+
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	} else if !bytes.Equal(output, expected) {
+		t.Errorf("output doesn't match %s, got:\n%s", golden, output)
+	}
+}
+
+// testdata is the absolute path of the testdata
+// directory, as the tests change directory.
+var testdata = func() string {
+	path, err := filepath.Abs("testdata")
+	if err != nil {
+		panic(err)
+	}
+
+	return path
+}()
+
+// chdirWorkspace changes the current directory, for the rest
+// of the test, to a temporary workspace containing the files
+// of an application bundle.
+func chdirWorkspace(t *testing.T) {
+	t.Helper()
+
+	workspace := t.TempDir()
+	for _, file := range []string{
+		"build/Example.app/Contents/MacOS/example",
+		"build/Example.app/Contents/Frameworks/Legacy.framework/Legacy",
+	} {
+		path := filepath.Join(workspace, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("create directory for %s: %v", file, err)
+		} else if err = os.WriteFile(path, []byte("binary"), 0755); err != nil {
+			t.Fatalf("write %s: %v", file, err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("get current directory: %v", err)
+	} else if err = os.Chdir(workspace); err != nil {
+		t.Fatalf("change to workspace: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatalf("restore current directory: %v", err)
+		}
+	})
+}
+
+// testAnnotations returns the annotations of a package
+// that failed with an error and two issues, followed by
+// one whose properties need escaping.
+func testAnnotations(t *testing.T) []Annotation {
+	t.Helper()
+	chdirWorkspace(t)
+
+	result := report.Package{
+		File:   "build/Example.app",
+		Status: "Accepted",
+		Errors: []string{"notarization log violates policy: error issue -1 at 'Example.zip/Example.app/Contents/MacOS/example' matched fail_on_severity 'error'"},
+	}
+
+	log := &api.NotarizationLog{
+		ArchiveFilename: "Example.zip",
+		Issues: []api.NotarizationIssue{
+			{
+				Severity:     "error",
+				Code:         "-1",
+				Path:         "Example.zip/Example.app/Contents/MacOS/example",
+				Message:      "The binary is not signed with a valid Developer ID certificate.",
+				DocUrl:       "https://developer.apple.com/documentation/security/resolving-common-notarization-issues",
+				Architecture: "arm64",
+			},
+			{
+				Severity: "warning",
+				Path:     "Example.zip/Example.app/Contents/Frameworks/Legacy.framework/Legacy",
+				Message:  "The binary uses an SDK older than the 10.9 SDK.",
+			},
+			{
+				Path:    "Example.zip/Example.app/Contents/Resources/missing.dylib",
+				Message: "The signature of the binary is invalid.",
+			},
+		},
+	}
+
+	return append(ForPackage(result, log), Annotation{
+		Severity: SeverityWarning,
+		File:     "build/Example: Tools, 100%.pkg",
+		Title:    "Notarization issue: 50%, maybe",
+		Message:  "first line\r\nsecond line: 100%, done",
+	})
+}
+
+func TestForPackage(t *testing.T) {
+	annotations := testAnnotations(t)
+
+	expected := []Annotation{
+		{SeverityFailure, "build/Example.app", "Notarization failed", "notarization log violates policy: error issue -1 at 'Example.zip/Example.app/Contents/MacOS/example' matched fail_on_severity 'error'"},
+		{SeverityError, "build/Example.app/Contents/MacOS/example", "Notarization error -1", "Example.zip/Example.app/Contents/MacOS/example (arm64): The binary is not signed with a valid Developer ID certificate.\nSee https://developer.apple.com/documentation/security/resolving-common-notarization-issues"},
+		{SeverityWarning, "build/Example.app/Contents/Frameworks/Legacy.framework/Legacy", "Notarization warning", "Example.zip/Example.app/Contents/Frameworks/Legacy.framework/Legacy: The binary uses an SDK older than the 10.9 SDK."},
+		{SeverityNotice, "build/Example.app", "Notarization issue", "Example.zip/Example.app/Contents/Resources/missing.dylib: The signature of the binary is invalid."},
+	}
+
+	for i := range expected {
+		if i >= len(annotations) {
+			t.Fatalf("expected %d annotations, got %d", len(expected)+1, len(annotations))
+		} else if annotations[i] != expected[i] {
+			t.Errorf("annotation %d = %+v, expected %+v", i, annotations[i], expected[i])
+		}
+	}
+}
+
+func TestWriteGitHub(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGitHub(&buf, testAnnotations(t)); err != nil {
+		t.Fatalf("WriteGitHub() returned error: %v", err)
+	}
+
+	checkGolden(t, "github.golden", buf.Bytes())
+}
+
+func TestWriteGitLab(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGitLab(&buf, testAnnotations(t)); err != nil {
+		t.Fatalf("WriteGitLab() returned error: %v", err)
+	}
+
+	checkGolden(t, "gitlab.json.golden", buf.Bytes())
+}
+
+func TestSourcePath(t *testing.T) {
+	chdirWorkspace(t)
+
+	tests := []struct {
+		archiveName, issuePath, expected string
+	}{
+		{"Example.zip", "Example.zip/Example.app/Contents/MacOS/example", "build/Example.app/Contents/MacOS/example"},
+		{"Example.zip", "Example.zip/Example.app", "build/Example.app"},
+
+		// Anything that can't be mapped to a file
+		// on disk is reported against the package
+		{"Example.zip", "Example.zip/Example.app/Contents/MacOS/missing", "build/Example.app"},
+		{"Example.zip", "Example.zip/Other.app/Contents/MacOS/example", "build/Example.app"},
+		{"Example.zip", "Other.zip/Example.app/Contents/MacOS/example", "build/Example.app"},
+		{"Example.zip", "Example.zip", "build/Example.app"},
+		{"", "Example.app/Contents/MacOS/example", "build/Example.app"},
+	}
+
+	for _, test := range tests {
+		if path := SourcePath("build/Example.app", test.archiveName, test.issuePath); filepath.ToSlash(path) != test.expected {
+			t.Errorf("SourcePath(%q, %q) = %q, expected %q", test.archiveName, test.issuePath, path, test.expected)
+		}
+	}
+}
+
+func TestGitLabFingerprint(t *testing.T) {
+	annotation := Annotation{SeverityWarning, "build/Example.app", "Notarization warning", "The binary uses an SDK older than the 10.9 SDK."}
+	moved := annotation
+	moved.File = "build/Other.app"
+
+	var buf bytes.Buffer
+	if err := WriteGitLab(&buf, []Annotation{annotation, annotation, moved}); err != nil {
+		t.Fatalf("WriteGitLab() returned error: %v", err)
+	}
+
+	var issues []gitlabIssue
+	if err := json.Unmarshal(buf.Bytes(), &issues); err != nil {
+		t.Fatalf("decode code quality report: %v", err)
+	}
+
+	// The fingerprint identifies an issue across
+	// pipelines, so it only depends on the file,
+	// title, and message
+	expected := sha256.Sum256([]byte("build/Example.app\x00Notarization warning\x00The binary uses an SDK older than the 10.9 SDK."))
+	if issues[0].Fingerprint != hex.EncodeToString(expected[:]) {
+		t.Errorf("fingerprint = %s, expected %x", issues[0].Fingerprint, expected)
+	} else if issues[1].Fingerprint != issues[0].Fingerprint {
+		t.Error("expected the same annotation to have the same fingerprint")
+	} else if issues[2].Fingerprint == issues[0].Fingerprint {
+		t.Error("expected an annotation for another file to have a different fingerprint")
+	}
+}
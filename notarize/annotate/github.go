@@ -0,0 +1,40 @@
+package annotate
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	githubDataEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+
+	githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// WriteGitHub writes the annotations as GitHub Actions
+// workflow commands, which must be written to stdout
+// for the runner to pick them up.
+func WriteGitHub(w io.Writer, annotations []Annotation) error {
+	for _, annotation := range annotations {
+		command := "notice"
+		switch annotation.Severity {
+		case SeverityFailure, SeverityError:
+			command = "error"
+
+		case SeverityWarning:
+			command = "warning"
+		}
+
+		_, err := fmt.Fprintf(w, "::%s file=%s,title=%s::%s\n", command,
+			githubPropertyEscaper.Replace(annotation.File),
+			githubPropertyEscaper.Replace(annotation.Title),
+			githubDataEscaper.Replace(annotation.Message),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package annotate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// gitlabIssue is an entry in a GitLab Code Quality report, see
+// https://docs.gitlab.com/ee/ci/testing/code_quality.html#code-quality-report-format
+type gitlabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitlabLocation `json:"location"`
+}
+
+type gitlabLocation struct {
+	Path  string `json:"path"`
+	Lines struct {
+		Begin int `json:"begin"`
+	} `json:"lines"`
+}
+
+// WriteGitLab writes the annotations as
+// a GitLab Code Quality report.
+func WriteGitLab(w io.Writer, annotations []Annotation) error {
+	issues := make([]gitlabIssue, 0, len(annotations))
+	for _, annotation := range annotations {
+		severity := "info"
+		switch annotation.Severity {
+		case SeverityFailure:
+			severity = "blocker"
+
+		case SeverityError:
+			severity = "critical"
+
+		case SeverityWarning:
+			severity = "minor"
+		}
+
+		fingerprint := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s", annotation.File, annotation.Title, annotation.Message)))
+		issue := gitlabIssue{
+			Description: fmt.Sprintf("%s: %s", annotation.Title, annotation.Message),
+			CheckName:   annotation.Title,
+			Fingerprint: hex.EncodeToString(fingerprint[:]),
+			Severity:    severity,
+			Location:    gitlabLocation{Path: annotation.File},
+		}
+
+		// Notarization issues aren't tied to a
+		// line but GitLab requires one
+		issue.Location.Lines.Begin = 1
+		issues = append(issues, issue)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(issues)
+}
+
+// WriteGitLabFile writes the annotations as a
+// GitLab Code Quality report to the file at path.
+func WriteGitLabFile(path string, annotations []Annotation) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open code quality report file: %w", err)
+	}
+	defer file.Close()
+
+	if err = WriteGitLab(file, annotations); err != nil {
+		return fmt.Errorf("write code quality report: %w", err)
+	}
+
+	return file.Close()
+}
@@ -2,17 +2,23 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
-	"os"
-	"strconv"
-	"sync"
+	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog"
+	"golang.org/x/net/http/httpproxy"
 )
 
 const (
 	notaryApiV2Base = "https://appstoreconnect.apple.com/notary/v2"
+
+	defaultUserAgent = "notarization-helper"
 )
 
 type (
@@ -21,28 +27,232 @@ type (
 	apiDoFunc func(req *resty.Request) (*resty.Response, error)
 )
 
-var (
-	httpClient     *resty.Client
-	httpClientOnce sync.Once
-)
+// ClientOption applies an optional setting
+// to a Client when it is created.
+type ClientOption func(client *clientOptions)
+
+type clientOptions struct {
+	endpoints Endpoints
+	transport http.RoundTripper
+	proxy     *url.URL
+	noProxy   string
+	rootCAs   *x509.CertPool
+	userAgent string
+	timeout   time.Duration
+	logger    *zerolog.Logger
+	debug     bool
+}
+
+// WithBaseURL overrides the base URL of the Notary API.
+func WithBaseURL(baseUrl string) ClientOption {
+	return func(client *clientOptions) {
+		client.endpoints.NotaryAPI = baseUrl
+	}
+}
+
+// WithEndpoints overrides all the endpoints used by
+// the Client, and by the workers that use it.
+func WithEndpoints(endpoints Endpoints) ClientOption {
+	return func(client *clientOptions) {
+		client.endpoints = endpoints
+	}
+}
+
+// WithTransport sets the http.RoundTripper used to
+// send requests, when set the proxy and root CAs
+// options are ignored as they can only be applied
+// to the default transport.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(client *clientOptions) {
+		client.transport = transport
+	}
+}
+
+// WithProxy sends all requests through the HTTP
+// proxy at the specified URL, credentials for an
+// authenticating proxy can be included in the URL.
+//
+// Without this option the proxy is taken from the
+// HTTPS_PROXY and HTTP_PROXY environment variables.
+func WithProxy(proxy *url.URL) ClientOption {
+	return func(client *clientOptions) {
+		client.proxy = proxy
+	}
+}
+
+// WithNoProxy specifies hosts, in the same format as
+// the NO_PROXY environment variable, that requests are
+// sent to directly rather than through the proxy, be it
+// set by WithProxy or taken from the environment.
+//
+// Without this option the hosts are taken from the
+// NO_PROXY environment variable.
+func WithNoProxy(noProxy string) ClientOption {
+	return func(client *clientOptions) {
+		client.noProxy = noProxy
+	}
+}
+
+// WithRootCAs replaces the set of root certificate
+// authorities used to verify TLS connections.
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return func(client *clientOptions) {
+		client.rootCAs = pool
+	}
+}
+
+// WithUserAgent sets the User-Agent
+// header sent with all requests.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(client *clientOptions) {
+		client.userAgent = userAgent
+	}
+}
 
-func getHttpClient() *resty.Client {
-	httpClientOnce.Do(func() {
-		httpClient = resty.New()
-		httpClient.SetBaseURL(notaryApiV2Base)
+// WithTimeout limits the time a single request
+// to the Notary API or CloudKit is allowed to
+// take, it doesn't apply to uploads made using
+// the HTTPClient of the Client.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(client *clientOptions) {
+		client.timeout = timeout
+	}
+}
+
+// WithLogger sets the logger that warnings,
+// errors and debug output of the Client is
+// written to.
+func WithLogger(logger zerolog.Logger) ClientOption {
+	return func(client *clientOptions) {
+		client.logger = &logger
+	}
+}
+
+// WithDebug enables the logging of
+// all requests and responses.
+func WithDebug(debug bool) ClientOption {
+	return func(client *clientOptions) {
+		client.debug = debug
+	}
+}
+
+// Client provides access to the Notary API and the
+// CloudKit ticket lookup, it is safe for concurrent
+// use and should be shared between workers.
+type Client struct {
+	http      *http.Client
+	resty     *resty.Client
+	endpoints Endpoints
+	timeout   time.Duration
+}
+
+// NewClient creates a new Client, without
+// any options the Client uses the services
+// operated by Apple.
+func NewClient(opts ...ClientOption) (*Client, error) {
+	options := &clientOptions{
+		endpoints: DefaultEndpoints(),
+		userAgent: defaultUserAgent,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
 
-		if trace, _ := strconv.ParseBool(os.Getenv("API_TRACE")); trace {
-			httpClient.SetDebug(true)
+	if _, err := url.Parse(options.endpoints.NotaryAPI); err != nil {
+		return nil, fmt.Errorf("parse notary API base URL: %w", err)
+	}
+
+	transport := options.transport
+	if transport == nil {
+		defaultTransport := http.DefaultTransport.(*http.Transport).Clone()
+
+		// The environment is read here, rather than by
+		// http.ProxyFromEnvironment, so that the options
+		// can override the proxy and hosts independently
+		proxyConfig := httpproxy.FromEnvironment()
+		if options.proxy != nil {
+			proxyConfig.HTTPProxy = options.proxy.String()
+			proxyConfig.HTTPSProxy = options.proxy.String()
+		}
+
+		if len(options.noProxy) > 0 {
+			proxyConfig.NoProxy = options.noProxy
+		}
+
+		proxyFunc := proxyConfig.ProxyFunc()
+		defaultTransport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFunc(req.URL)
+		}
+
+		if options.rootCAs != nil {
+			if defaultTransport.TLSClientConfig == nil {
+				defaultTransport.TLSClientConfig = new(tls.Config)
+			}
+
+			defaultTransport.TLSClientConfig.RootCAs = options.rootCAs
 		}
-	})
 
-	return httpClient
+		transport = defaultTransport
+	}
+
+	client := &Client{
+		http: &http.Client{
+			Transport: transport,
+		},
+		endpoints: options.endpoints,
+		timeout:   options.timeout,
+	}
+
+	client.resty = resty.NewWithClient(client.http)
+	client.resty.SetBaseURL(options.endpoints.NotaryAPI)
+	client.resty.SetHeader("User-Agent", options.userAgent)
+	client.resty.SetDebug(options.debug)
+
+	if options.logger != nil {
+		client.resty.SetLogger(restyLogger{*options.logger})
+	}
+
+	return client, nil
 }
 
-func newApiRequest[T any](ctx context.Context, auth RequestAuthentication, body any, do apiDoFunc) (*ApiResponse[T], error) {
-	req := getHttpClient().NewRequest()
+// Endpoints returns the endpoints used by the Client.
+func (client *Client) Endpoints() Endpoints {
+	return client.endpoints
+}
+
+// HTTPClient returns the underlying http.Client
+// so that other services, such as S3, can be
+// accessed using the same transport settings.
+//
+// The http.Client has no timeout, as an upload
+// of a large package may take a long time, the
+// timeout set by WithTimeout is only applied to
+// the requests the Client makes itself.
+func (client *Client) HTTPClient() *http.Client {
+	return client.http
+}
+
+// newRequest creates a request that is cancelled
+// along with ctx or once the timeout set by
+// WithTimeout expires, cancel must be called once
+// the response has been read.
+func (client *Client) newRequest(ctx context.Context) (req *resty.Request, cancel context.CancelFunc) {
+	cancel = func() {}
+	if client.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, client.timeout)
+	}
+
+	req = client.resty.NewRequest()
 	req.SetContext(ctx)
 
+	return req, cancel
+}
+
+func newApiRequest[T any](client *Client, ctx context.Context, auth RequestAuthentication, body any, do apiDoFunc) (*ApiResponse[T], error) {
+	req, cancel := client.newRequest(ctx)
+	defer cancel()
+
 	if err := auth(req); err != nil {
 		return nil, fmt.Errorf("apply request authentication: %w", err)
 	}
@@ -78,8 +288,8 @@ func newApiRequest[T any](ctx context.Context, auth RequestAuthentication, body
 	}
 }
 
-func GetAppSpecificPasswordToken(ctx context.Context, auth RequestAuthentication) (*AppSpecificPasswordResponse, error) {
-	apiResp, err := newApiRequest[AppSpecificPasswordResponse](ctx, auth, nil,
+func (client *Client) GetAppSpecificPasswordToken(ctx context.Context, auth RequestAuthentication) (*AppSpecificPasswordResponse, error) {
+	apiResp, err := newApiRequest[AppSpecificPasswordResponse](client, ctx, auth, nil,
 		func(req *resty.Request) (*resty.Response, error) {
 			return req.Get("/asp")
 		},
@@ -93,8 +303,8 @@ func GetAppSpecificPasswordToken(ctx context.Context, auth RequestAuthentication
 	return &resp, nil
 }
 
-func StartNewSubmission(ctx context.Context, auth RequestAuthentication, subRequest *SubmissionRequest) (*SubmissionResponse, error) {
-	apiResp, err := newApiRequest[SubmissionResponse](ctx, auth, subRequest,
+func (client *Client) StartNewSubmission(ctx context.Context, auth RequestAuthentication, subRequest *SubmissionRequest) (*SubmissionResponse, error) {
+	apiResp, err := newApiRequest[SubmissionResponse](client, ctx, auth, subRequest,
 		func(req *resty.Request) (*resty.Response, error) {
 			return req.Post("/submissions")
 		},
@@ -109,8 +319,8 @@ func StartNewSubmission(ctx context.Context, auth RequestAuthentication, subRequ
 	return &resp, nil
 }
 
-func GetSubmissionStatus(ctx context.Context, auth RequestAuthentication, submissionId string) (*SubmissionStatusResponse, error) {
-	apiResp, err := newApiRequest[SubmissionStatusResponse](ctx, auth, nil,
+func (client *Client) GetSubmissionStatus(ctx context.Context, auth RequestAuthentication, submissionId string) (*SubmissionStatusResponse, error) {
+	apiResp, err := newApiRequest[SubmissionStatusResponse](client, ctx, auth, nil,
 		func(req *resty.Request) (*resty.Response, error) {
 			req.SetPathParam("submissionId", submissionId)
 			return req.Get("/submissions/{submissionId}")
@@ -125,8 +335,8 @@ func GetSubmissionStatus(ctx context.Context, auth RequestAuthentication, submis
 	return &resp, nil
 }
 
-func GetSubmissionLogURL(ctx context.Context, auth RequestAuthentication, submissionId string) (*SubmissionLogURLResponse, error) {
-	apiResp, err := newApiRequest[SubmissionLogURLResponse](ctx, auth, nil,
+func (client *Client) GetSubmissionLogURL(ctx context.Context, auth RequestAuthentication, submissionId string) (*SubmissionLogURLResponse, error) {
+	apiResp, err := newApiRequest[SubmissionLogURLResponse](client, ctx, auth, nil,
 		func(req *resty.Request) (*resty.Response, error) {
 			req.SetPathParam("submissionId", submissionId)
 			return req.Get("/submissions/{submissionId}/logs")
@@ -141,9 +351,9 @@ func GetSubmissionLogURL(ctx context.Context, auth RequestAuthentication, submis
 	return &resp, nil
 }
 
-func DownloadNotaryLog(ctx context.Context, logUrl string) (*NotarizationLog, error) {
-	req := getHttpClient().NewRequest()
-	req.SetContext(ctx)
+func (client *Client) DownloadNotaryLog(ctx context.Context, logUrl string) (*NotarizationLog, error) {
+	req, cancel := client.newRequest(ctx)
+	defer cancel()
 
 	req.SetHeader("Accept", "application/json")
 	// Apple doesn't seem to set the content type
@@ -166,13 +376,13 @@ func DownloadNotaryLog(ctx context.Context, logUrl string) (*NotarizationLog, er
 	}
 }
 
-func GetTickets(ctx context.Context, records []TicketRecord) ([]TicketRecord, error) {
+func (client *Client) GetTickets(ctx context.Context, records []TicketRecord) ([]TicketRecord, error) {
 	recs := &struct {
 		Records []TicketRecord `json:"records"`
 	}{records}
 
-	req := getHttpClient().NewRequest()
-	req.SetContext(ctx)
+	req, cancel := client.newRequest(ctx)
+	defer cancel()
 	req.SetHeaders(map[string]string{
 		"Content-Type": "application/json",
 		"Accept":       "application/json",
@@ -180,7 +390,7 @@ func GetTickets(ctx context.Context, records []TicketRecord) ([]TicketRecord, er
 	req.SetBody(recs)
 	req.SetResult(recs)
 
-	resp, err := req.Post("https://api.apple-cloudkit.com/database/1/com.apple.gk.ticket-delivery/production/public/records/lookup")
+	resp, err := req.Post(client.endpoints.TicketLookup)
 	switch {
 	case err == nil && resp.IsError():
 		err = fmt.Errorf("api error: %s (%d)", resp.Status(), resp.StatusCode())
@@ -196,3 +406,21 @@ func GetTickets(ctx context.Context, records []TicketRecord) ([]TicketRecord, er
 		return recs.Records, nil
 	}
 }
+
+// restyLogger adapts a zerolog.Logger
+// to the logger interface used by Resty.
+type restyLogger struct {
+	logger zerolog.Logger
+}
+
+func (l restyLogger) Errorf(format string, v ...any) {
+	l.logger.Error().Msgf(format, v...)
+}
+
+func (l restyLogger) Warnf(format string, v ...any) {
+	l.logger.Warn().Msgf(format, v...)
+}
+
+func (l restyLogger) Debugf(format string, v ...any) {
+	l.logger.Debug().Msgf(format, v...)
+}
@@ -0,0 +1,38 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+	"github.com/go-resty/resty/v2"
+)
+
+func TestClientTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	client, err := api.NewClient(api.WithBaseURL(srv.URL), api.WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+
+	noAuth := func(*resty.Request) error { return nil }
+	if _, err = client.GetSubmissionStatus(context.Background(), noAuth, "submission"); err == nil {
+		t.Error("expected the API request to time out")
+	}
+
+	// The timeout must not apply to uploads, which
+	// share the transport of the client
+	resp, err := client.HTTPClient().Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request using the HTTP client of the Client failed: %v", err)
+	}
+	_ = resp.Body.Close()
+}
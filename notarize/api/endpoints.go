@@ -0,0 +1,45 @@
+package api
+
+const (
+	defaultTicketLookupUrl = "https://api.apple-cloudkit.com/database/1/com.apple.gk.ticket-delivery/production/public/records/lookup"
+	defaultS3Region        = "us-west-2"
+)
+
+// Endpoints specifies the location of the services
+// used during notarization, allowing them to be
+// replaced by a mirror, proxy, or local fake.
+type Endpoints struct {
+	// NotaryAPI is the base URL of the
+	// Notary v2 API.
+	NotaryAPI string
+
+	// TicketLookup is the URL of the CloudKit
+	// records lookup endpoint tickets are
+	// downloaded from for stapling.
+	TicketLookup string
+
+	// S3Region is the AWS region of the bucket
+	// submissions are uploaded to.
+	S3Region string
+
+	// S3Endpoint, if set, overrides the endpoint
+	// used for the S3 bucket, requests to it use
+	// path-style addressing.
+	S3Endpoint string
+
+	// S3Accelerate specifies if S3 Transfer
+	// Acceleration should be used, it is ignored
+	// if S3Endpoint is set.
+	S3Accelerate bool
+}
+
+// DefaultEndpoints returns the endpoints
+// of the services operated by Apple.
+func DefaultEndpoints() Endpoints {
+	return Endpoints{
+		NotaryAPI:    notaryApiV2Base,
+		TicketLookup: defaultTicketLookupUrl,
+		S3Region:     defaultS3Region,
+		S3Accelerate: true,
+	}
+}
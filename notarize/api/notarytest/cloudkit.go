@@ -0,0 +1,35 @@
+package notarytest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+)
+
+// handleTicketLookup fakes the CloudKit records lookup
+// used to download tickets, only tickets for accepted
+// submissions whose log has been downloaded are known.
+func (server *Server) handleTicketLookup(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Records []api.TicketRecord `json:"records"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	server.lock.Lock()
+	for i, record := range req.Records {
+		if ticket, found := server.tickets[record.RecordName]; found {
+			req.Records[i].Fields.Ticket.Value = ticket
+		} else {
+			req.Records[i].ErrorCode = "NOT_FOUND"
+		}
+	}
+	server.lock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(req)
+}
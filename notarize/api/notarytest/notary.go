@@ -0,0 +1,243 @@
+package notarytest
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+)
+
+type apiData struct {
+	Id         string `json:"id,omitempty"`
+	Type       string `json:"type"`
+	Attributes any    `json:"attributes"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeApiData(w http.ResponseWriter, data apiData) {
+	writeJSON(w, http.StatusOK, map[string]any{"data": data})
+}
+
+func writeApiError(w http.ResponseWriter, status int, code, detail string) {
+	writeJSON(w, status, api.ErrorResponse{Errors: []api.ErrorResponseErr{{
+		Status: fmt.Sprint(status),
+		Code:   code,
+		Title:  http.StatusText(status),
+		Detail: detail,
+	}}})
+}
+
+func authorized(w http.ResponseWriter, r *http.Request) bool {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		writeApiError(w, http.StatusUnauthorized, "NOT_AUTHORIZED", "missing bearer token")
+		return false
+	}
+
+	return true
+}
+
+func (server *Server) handleAsp(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := r.BasicAuth(); !ok {
+		writeApiError(w, http.StatusUnauthorized, "NOT_AUTHORIZED", "missing basic credentials")
+		return
+	}
+
+	writeApiData(w, apiData{Type: "asp", Attributes: api.AppSpecificPasswordResponse{Token: "fake-asp-token"}})
+}
+
+func (server *Server) handleNewSubmission(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+
+	var req api.SubmissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeApiError(w, http.StatusBadRequest, "PARAMETER_ERROR.INVALID", err.Error())
+		return
+	} else if len(req.Name) == 0 || len(req.Hash) != sha256.Size*2 {
+		writeApiError(w, http.StatusBadRequest, "PARAMETER_ERROR.INVALID", "submissionName and sha256 are required")
+		return
+	}
+
+	server.lock.Lock()
+	server.nextId++
+	id := fmt.Sprintf("00000000-0000-4000-8000-%012d", server.nextId)
+
+	scenario, found := server.scenarios[req.Name]
+	if !found {
+		scenario = server.defaultScenario
+	}
+
+	server.submissions[id] = &Submission{
+		ID:       id,
+		Name:     req.Name,
+		SHA256:   req.Hash,
+		Scenario: scenario,
+		Status:   "In Progress",
+	}
+	server.order = append(server.order, id)
+	server.lock.Unlock()
+
+	writeApiData(w, apiData{Id: id, Type: "newSubmissions", Attributes: map[string]string{
+		"awsAccessKeyId":     "AKIAFAKEFAKEFAKE",
+		"awsSecretAccessKey": "fake-secret-access-key",
+		"awsSessionToken":    "fake-session-token",
+		"bucket":             fakeBucket,
+		"object":             path.Join("prod", id),
+	}})
+}
+
+func (server *Server) handleSubmissionStatus(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !authorized(w, r) || !server.beginRequest(w, id) {
+		return
+	}
+
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	sub, found := server.submissions[id]
+	if !found {
+		writeApiError(w, http.StatusNotFound, "NOT_FOUND", "submission not found")
+		return
+	}
+
+	// A submission only progresses once its
+	// upload has been completed
+	if len(sub.Uploaded) > 0 || sub.Parts > 0 {
+		if sub.Polls >= sub.Scenario.Polls {
+			sub.Status = string(sub.outcome())
+		}
+
+		sub.Polls++
+	}
+
+	writeApiData(w, apiData{Id: id, Type: "submissions", Attributes: map[string]string{
+		"createdDate": time.Now().UTC().Format(time.RFC3339),
+		"name":        sub.Name,
+		"status":      sub.Status,
+	}})
+}
+
+func (server *Server) handleSubmissionLogs(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !authorized(w, r) || !server.beginRequest(w, id) {
+		return
+	}
+
+	server.lock.Lock()
+	_, found := server.submissions[id]
+	server.lock.Unlock()
+
+	if !found {
+		writeApiError(w, http.StatusNotFound, "NOT_FOUND", "submission not found")
+		return
+	}
+
+	writeApiData(w, apiData{Id: id, Type: "submissionsLog", Attributes: api.SubmissionLogURLResponse{
+		DeveloperLogUrl: server.URL + logsPrefix + id,
+	}})
+}
+
+func (server *Server) handleLogDownload(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !server.beginRequest(w, id) {
+		return
+	}
+
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	sub, found := server.submissions[id]
+	if !found || sub.Status == "In Progress" {
+		http.Error(w, "log not available", http.StatusNotFound)
+		return
+	}
+
+	type ticket struct {
+		Path            string `json:"path"`
+		DigestAlgorithm string `json:"digestAlgorithm"`
+		CDHash          string `json:"cdhash"`
+		Arch            string `json:"arch"`
+	}
+
+	var tickets []ticket
+	if sub.outcome() == OutcomeAccepted {
+		for _, ticketPath := range sub.ticketPaths() {
+			cdHash := sha1.Sum([]byte(sub.SHA256 + ticketPath))
+			record := api.NotarizationTicket{DigestAlgorithm: api.DigestAlgorithmSHA256, CDHash: hex.EncodeToString(cdHash[:])}
+			server.tickets[record.RecordName()] = []byte(fmt.Sprintf("fake-ticket:%s", record.CDHash))
+
+			tickets = append(tickets, ticket{
+				Path:            ticketPath,
+				DigestAlgorithm: record.DigestAlgorithm.String(),
+				CDHash:          record.CDHash,
+				Arch:            "arm64",
+			})
+		}
+	}
+
+	statusCode, statusSummary := 0, "Ready for distribution"
+	if sub.outcome() != OutcomeAccepted {
+		statusCode, statusSummary = 4000, "Archive contains critical validation errors"
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"jobId":           sub.ID,
+		"status":          sub.Status,
+		"statusSummary":   statusSummary,
+		"statusCode":      statusCode,
+		"archiveFilename": sub.Name,
+		"uploadDate":      time.Now().UTC().Format(time.RFC3339),
+		"sha256":          sub.SHA256,
+		"ticketContents":  tickets,
+		"issues":          sub.Scenario.Issues,
+	})
+}
+
+func (sub *Submission) outcome() Outcome {
+	if len(sub.Scenario.Outcome) == 0 {
+		return OutcomeAccepted
+	}
+
+	return sub.Scenario.Outcome
+}
+
+// ticketPaths returns the paths the Notary would issue
+// tickets for, the submission itself and, if it is a ZIP,
+// each top level entry of the ZIP.
+func (sub *Submission) ticketPaths() []string {
+	paths := []string{sub.Name}
+
+	archive, err := zip.NewReader(bytes.NewReader(sub.Uploaded), int64(len(sub.Uploaded)))
+	if err != nil {
+		return paths
+	}
+
+	seen := make(map[string]bool)
+	for _, file := range archive.File {
+		top, _, _ := strings.Cut(file.Name, "/")
+		if top == "__MACOSX" || seen[top] {
+			continue
+		}
+
+		seen[top] = true
+		paths = append(paths, path.Join(sub.Name, top))
+	}
+
+	return paths
+}
@@ -0,0 +1,212 @@
+package notarytest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+type multipartUpload struct {
+	submissionId string
+	parts        map[int32][]byte
+}
+
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+func writeXML(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, xml.Header)
+	_ = xml.NewEncoder(w).Encode(body)
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code, message string) {
+	writeXML(w, status, s3Error{Code: code, Message: message})
+}
+
+// handleS3 implements the subset of the S3 API, using
+// path-style addressing, that is used to upload a
+// submission with a multipart upload.
+func (server *Server) handleS3(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, s3Prefix+"/"), "/")
+	if bucket != fakeBucket {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+
+	submissionId := path.Base(key)
+	if !server.beginRequest(w, submissionId) {
+		return
+	}
+
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		server.handleCreateMultipartUpload(w, bucket, key, submissionId)
+
+	case r.Method == http.MethodPut && query.Has("partNumber") && query.Has("uploadId"):
+		server.handleUploadPart(w, r, query.Get("uploadId"), query.Get("partNumber"))
+
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		server.handleCompleteMultipartUpload(w, r, bucket, key, query.Get("uploadId"))
+
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		server.handleAbortMultipartUpload(w, query.Get("uploadId"))
+
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented", "operation not supported by fake")
+	}
+}
+
+func (server *Server) handleCreateMultipartUpload(w http.ResponseWriter, bucket, key, submissionId string) {
+	server.lock.Lock()
+	if _, found := server.submissions[submissionId]; !found {
+		server.lock.Unlock()
+		writeS3Error(w, http.StatusForbidden, "AccessDenied", "no submission for object")
+		return
+	}
+
+	uploadId := fmt.Sprintf("upload-%s", submissionId)
+	server.uploads[uploadId] = &multipartUpload{
+		submissionId: submissionId,
+		parts:        make(map[int32][]byte),
+	}
+	server.lock.Unlock()
+
+	writeXML(w, http.StatusOK, struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Bucket   string   `xml:"Bucket"`
+		Key      string   `xml:"Key"`
+		UploadId string   `xml:"UploadId"`
+	}{Bucket: bucket, Key: key, UploadId: uploadId})
+}
+
+func (server *Server) handleUploadPart(w http.ResponseWriter, r *http.Request, uploadId, partNumber string) {
+	number, err := strconv.ParseInt(partNumber, 10, 32)
+	if err != nil || number < 1 || number > 10000 {
+		writeS3Error(w, http.StatusBadRequest, "InvalidArgument", "invalid part number")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeS3Error(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	checksum := sha256.Sum256(body)
+	encodedChecksum := base64.StdEncoding.EncodeToString(checksum[:])
+	if expected := r.Header.Get("X-Amz-Checksum-Sha256"); len(expected) > 0 && expected != encodedChecksum {
+		writeS3Error(w, http.StatusBadRequest, "BadDigest", "the SHA256 checksum of the part did not match")
+		return
+	}
+
+	server.lock.Lock()
+	upload, found := server.uploads[uploadId]
+	if found {
+		upload.parts[int32(number)] = body
+	}
+	server.lock.Unlock()
+
+	if !found {
+		writeS3Error(w, http.StatusNotFound, "NoSuchUpload", "the specified upload does not exist")
+		return
+	}
+
+	etag := md5.Sum(body)
+	w.Header().Set("ETag", fmt.Sprintf("%q", hex.EncodeToString(etag[:])))
+	w.Header().Set("X-Amz-Checksum-Sha256", encodedChecksum)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (server *Server) handleCompleteMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key, uploadId string) {
+	var req struct {
+		Parts []struct {
+			PartNumber     int32  `xml:"PartNumber"`
+			ETag           string `xml:"ETag"`
+			ChecksumSHA256 string `xml:"ChecksumSHA256"`
+		} `xml:"Part"`
+	}
+
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeS3Error(w, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	upload, found := server.uploads[uploadId]
+	if !found {
+		writeS3Error(w, http.StatusNotFound, "NoSuchUpload", "the specified upload does not exist")
+		return
+	}
+
+	var assembled bytes.Buffer
+	for i, part := range req.Parts {
+		body, uploaded := upload.parts[part.PartNumber]
+
+		switch {
+		case i > 0 && part.PartNumber <= req.Parts[i-1].PartNumber:
+			writeS3Error(w, http.StatusBadRequest, "InvalidPartOrder", "parts must be listed in ascending order")
+			return
+
+		case !uploaded:
+			writeS3Error(w, http.StatusBadRequest, "InvalidPart", fmt.Sprintf("part %d was not uploaded", part.PartNumber))
+			return
+
+		case len(part.ChecksumSHA256) > 0:
+			checksum := sha256.Sum256(body)
+			if part.ChecksumSHA256 != base64.StdEncoding.EncodeToString(checksum[:]) {
+				writeS3Error(w, http.StatusBadRequest, "InvalidPart", fmt.Sprintf("checksum of part %d did not match", part.PartNumber))
+				return
+			}
+		}
+
+		assembled.Write(body)
+	}
+
+	sub := server.submissions[upload.submissionId]
+	sub.Uploaded = assembled.Bytes()
+	sub.Parts = len(req.Parts)
+	delete(server.uploads, uploadId)
+
+	if hash := sha256.Sum256(sub.Uploaded); hex.EncodeToString(hash[:]) != sub.SHA256 {
+		// The real Notary accepts the upload and
+		// reports the mismatch as an invalid
+		// submission so the fake does the same
+		sub.Scenario.Outcome = OutcomeInvalid
+	}
+
+	writeXML(w, http.StatusOK, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Bucket  string   `xml:"Bucket"`
+		Key     string   `xml:"Key"`
+		ETag    string   `xml:"ETag"`
+	}{Bucket: bucket, Key: key, ETag: fmt.Sprintf("%q", upload.submissionId)})
+}
+
+func (server *Server) handleAbortMultipartUpload(w http.ResponseWriter, uploadId string) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	if upload, found := server.uploads[uploadId]; found {
+		server.submissions[upload.submissionId].Aborted = true
+		delete(server.uploads, uploadId)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
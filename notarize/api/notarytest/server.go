@@ -0,0 +1,216 @@
+// Package notarytest provides an in-process fake of the
+// services used during notarization, the Notary v2 API,
+// the S3 bucket submissions are uploaded to, and the
+// CloudKit ticket lookup, for use in integration tests.
+package notarytest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+)
+
+const (
+	notaryPrefix   = "/notary/v2"
+	s3Prefix       = "/s3"
+	logsPrefix     = "/logs/"
+	cloudKitPrefix = "/cloudkit/lookup"
+
+	fakeBucket = "notary-submissions"
+)
+
+// Outcome specifies the final status
+// the fake reports for a submission.
+type Outcome string
+
+const (
+	OutcomeAccepted Outcome = "Accepted"
+	OutcomeInvalid  Outcome = "Invalid"
+	OutcomeRejected Outcome = "Rejected"
+)
+
+// Scenario scripts how the fake behaves
+// for a submission.
+type Scenario struct {
+	// Outcome is the final status of the
+	// submission, defaults to OutcomeAccepted.
+	Outcome Outcome
+
+	// Polls is the number of times the submission
+	// status is reported as in progress, after the
+	// upload completes, before Outcome is reported.
+	Polls int
+
+	// Delay is added to every response made
+	// for the submission, simulating a slow
+	// service.
+	Delay time.Duration
+
+	// FailRequests is the number of requests made
+	// for the submission, across all endpoints, that
+	// fail with a 503 before the fake starts to
+	// respond normally, simulating a flaky service.
+	FailRequests int
+
+	// Issues are included in the notarization
+	// log for the submission.
+	Issues []api.NotarizationIssue
+}
+
+// Submission records the state of a
+// submission made to the fake.
+type Submission struct {
+	ID       string
+	Name     string
+	SHA256   string
+	Scenario Scenario
+
+	// Uploaded holds the assembled contents of
+	// the completed S3 multipart upload.
+	Uploaded []byte
+	Parts    int
+	Aborted  bool
+	Polls    int
+	Status   string
+
+	failures int
+}
+
+// Option applies an optional setting
+// to a Server when it is created.
+type Option func(server *Server)
+
+// WithScenario sets the scenario used for
+// submissions with the specified name.
+func WithScenario(submissionName string, scenario Scenario) Option {
+	return func(server *Server) {
+		server.scenarios[submissionName] = scenario
+	}
+}
+
+// WithDefaultScenario sets the scenario used for
+// submissions that don't have a specific scenario.
+func WithDefaultScenario(scenario Scenario) Option {
+	return func(server *Server) {
+		server.defaultScenario = scenario
+	}
+}
+
+// Server is a fake of the Notary v2 API, S3 and
+// CloudKit ticket lookup, served from a single
+// httptest.Server.
+type Server struct {
+	*httptest.Server
+
+	lock            sync.Mutex
+	defaultScenario Scenario
+	scenarios       map[string]Scenario
+	submissions     map[string]*Submission
+	order           []string
+	uploads         map[string]*multipartUpload
+	tickets         map[string][]byte
+	nextId          int
+}
+
+// NewServer starts a new fake, it must be
+// closed once it is no longer needed.
+func NewServer(opts ...Option) *Server {
+	server := &Server{
+		scenarios:   make(map[string]Scenario),
+		submissions: make(map[string]*Submission),
+		uploads:     make(map[string]*multipartUpload),
+		tickets:     make(map[string][]byte),
+	}
+
+	for _, opt := range opts {
+		opt(server)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+notaryPrefix+"/asp", server.handleAsp)
+	mux.HandleFunc("POST "+notaryPrefix+"/submissions", server.handleNewSubmission)
+	mux.HandleFunc("GET "+notaryPrefix+"/submissions/{id}", server.handleSubmissionStatus)
+	mux.HandleFunc("GET "+notaryPrefix+"/submissions/{id}/logs", server.handleSubmissionLogs)
+	mux.HandleFunc("GET "+logsPrefix+"{id}", server.handleLogDownload)
+	mux.HandleFunc(s3Prefix+"/", server.handleS3)
+	mux.HandleFunc("POST "+cloudKitPrefix, server.handleTicketLookup)
+
+	server.Server = httptest.NewServer(mux)
+	return server
+}
+
+// Endpoints returns the endpoints that point
+// the notarization helper at this fake.
+func (server *Server) Endpoints() api.Endpoints {
+	return api.Endpoints{
+		NotaryAPI:    server.URL + notaryPrefix,
+		TicketLookup: server.URL + cloudKitPrefix,
+		S3Region:     "us-west-2",
+		S3Endpoint:   server.URL + s3Prefix,
+	}
+}
+
+// NewApiClient creates an api.Client that sends its
+// requests to this fake, additional options are
+// applied after the endpoints of the fake.
+func (server *Server) NewApiClient(opts ...api.ClientOption) (*api.Client, error) {
+	return api.NewClient(append([]api.ClientOption{api.WithEndpoints(server.Endpoints())}, opts...)...)
+}
+
+// Submissions returns a copy of every submission
+// made to the fake, in the order they were made.
+func (server *Server) Submissions() []Submission {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	submissions := make([]Submission, 0, len(server.order))
+	for _, id := range server.order {
+		sub := *server.submissions[id]
+		sub.Uploaded = slices.Clone(sub.Uploaded)
+		submissions = append(submissions, sub)
+	}
+
+	return submissions
+}
+
+// Ticket returns the signed ticket served by the fake
+// for the specified CloudKit record, tickets are only
+// available for accepted submissions.
+func (server *Server) Ticket(recordName string) ([]byte, bool) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	ticket, found := server.tickets[recordName]
+	return slices.Clone(ticket), found
+}
+
+// beginRequest applies the delay and failures scripted
+// for the submission, returning false if the request
+// should fail, it must be called without the lock held.
+func (server *Server) beginRequest(w http.ResponseWriter, submissionId string) bool {
+	server.lock.Lock()
+	sub, found := server.submissions[submissionId]
+	if !found {
+		server.lock.Unlock()
+		return true
+	}
+
+	delay := sub.Scenario.Delay
+	fail := sub.failures < sub.Scenario.FailRequests
+	if fail {
+		sub.failures++
+	}
+	server.lock.Unlock()
+
+	time.Sleep(delay)
+	if fail {
+		http.Error(w, "service temporarily unavailable", http.StatusServiceUnavailable)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,225 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+)
+
+const (
+	appleDoubleMagic   = 0x00051607
+	appleDoubleVersion = 0x00020000
+
+	appleDoubleEntryResourceFork = 2
+	appleDoubleEntryFinderInfo   = 9
+
+	// appleDoubleFinderInfoOffset is the offset of
+	// the Finder info entry data which immediately
+	// follows the AppleDouble header and its two
+	// entry descriptors.
+	appleDoubleFinderInfoOffset = 50
+	appleDoubleFinderInfoSize   = 32
+
+	attrHeaderMagic = 0x41545452 // 'ATTR'
+	attrHeaderSize  = 36
+
+	// appleDoubleMinSize is the size copyfile(3) pads
+	// AppleDouble files to, the resource fork is placed
+	// at the end of it unless the attributes don't fit.
+	appleDoubleMinSize = 4096
+
+	// emptyResourceForkFirst is the offset of both the
+	// (empty) data and the map of an empty resource fork,
+	// and emptyResourceForkMapSize the size of its map.
+	emptyResourceForkFirst   = 256
+	emptyResourceForkMapSize = 30
+	emptyResourceForkTag     = "This resource fork intentionally left blank   "
+
+	xattrFinderInfo   = "com.apple.FinderInfo"
+	xattrResourceFork = "com.apple.ResourceFork"
+)
+
+var (
+	appleDoubleFiller = [16]byte{'M', 'a', 'c', ' ', 'O', 'S', ' ', 'X', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' '}
+)
+
+type appleDoubleHeader struct {
+	Magic      uint32
+	Version    uint32
+	Filler     [16]byte
+	NumEntries uint16
+	Entries    [2]appleDoubleEntry
+	FinderInfo [appleDoubleFinderInfoSize]byte
+	Pad        [2]byte
+}
+
+type appleDoubleEntry struct {
+	ID     uint32
+	Offset uint32
+	Length uint32
+}
+
+// emptyResourceFork is the resource fork copyfile(3)
+// writes for a file that doesn't have one, a resource
+// fork header followed by a map without any resources.
+type emptyResourceFork struct {
+	DataOffset uint32
+	MapOffset  uint32
+	DataLength uint32
+	MapLength  uint32
+	SystemData [112]byte
+	AppData    [128]byte
+
+	MapDataOffset  uint32
+	MapMapOffset   uint32
+	MapDataLength  uint32
+	MapMapLength   uint32
+	MapNext        uint32
+	MapRefNum      uint16
+	MapAttr        uint8
+	MapMemoryAttr  uint8
+	MapTypesOffset uint16
+	MapNamesOffset uint16
+	MapTypeCount   uint16
+}
+
+// encodeEmptyResourceFork returns the
+// encoding of an empty resource fork.
+func encodeEmptyResourceFork() []byte {
+	fork := emptyResourceFork{
+		DataOffset:    emptyResourceForkFirst,
+		MapOffset:     emptyResourceForkFirst,
+		MapLength:     emptyResourceForkMapSize,
+		MapDataOffset: emptyResourceForkFirst,
+		MapMapOffset:  emptyResourceForkFirst,
+		MapMapLength:  emptyResourceForkMapSize,
+
+		// The type list immediately follows the map header
+		// and is empty, a count of -1 meaning no types
+		MapTypesOffset: emptyResourceForkMapSize - 2,
+		MapNamesOffset: emptyResourceForkMapSize,
+		MapTypeCount:   0xFFFF,
+	}
+	copy(fork.SystemData[:], emptyResourceForkTag)
+
+	var out bytes.Buffer
+	_ = binary.Write(&out, binary.BigEndian, fork)
+
+	return out.Bytes()
+}
+
+type attrHeader struct {
+	Magic      uint32
+	DebugTag   uint32
+	TotalSize  uint32
+	DataStart  uint32
+	DataLength uint32
+	Reserved   [3]uint32
+	Flags      uint16
+	NumAttrs   uint16
+}
+
+// encodeAppleDouble produces an AppleDouble file, in
+// the layout written by copyfile(3) and ditto(1), that
+// carries the supplied extended attributes.
+//
+// The Finder info and resource fork are stored in their
+// dedicated AppleDouble entries while all other attributes
+// are stored in the extended attribute area that follows
+// the Finder info. A file without a resource fork is given
+// an empty one and, as copyfile(3) does, the attribute area
+// is padded so that the resource fork ends the file at
+// appleDoubleMinSize bytes, if the attributes fit.
+func encodeAppleDouble(attrs map[string][]byte) []byte {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		if name != xattrFinderInfo && name != xattrResourceFork {
+			names = append(names, name)
+		}
+	}
+
+	// Attributes are written in a stable order so
+	// that the same input produces the same output
+	slices.Sort(names)
+
+	headerSize := uint32(binary.Size(appleDoubleHeader{}))
+
+	var entries bytes.Buffer
+	entriesSize := uint32(0)
+	for _, name := range names {
+		entriesSize += attrEntrySize(name)
+	}
+
+	dataStart := headerSize + attrHeaderSize + entriesSize
+	dataOffset := dataStart
+
+	var data bytes.Buffer
+	for _, name := range names {
+		value := attrs[name]
+
+		_ = binary.Write(&entries, binary.BigEndian, struct {
+			Offset  uint32
+			Length  uint32
+			Flags   uint16
+			NameLen uint8
+		}{dataOffset, uint32(len(value)), 0, uint8(len(name) + 1)})
+
+		entries.WriteString(name)
+		entries.WriteByte(0)
+		for entries.Len()%4 != 0 {
+			entries.WriteByte(0)
+		}
+
+		data.Write(value)
+		dataOffset += uint32(len(value))
+	}
+
+	resourceFork, found := attrs[xattrResourceFork]
+	if !found {
+		resourceFork = encodeEmptyResourceFork()
+	}
+
+	// The resource fork starts where the attribute
+	// area ends, which is its size in total_size
+	totalSize := dataStart + uint32(data.Len())
+	if padded := appleDoubleMinSize - uint32(len(resourceFork)); len(resourceFork) < appleDoubleMinSize && padded > totalSize {
+		totalSize = padded
+	}
+
+	header := appleDoubleHeader{
+		Magic:      appleDoubleMagic,
+		Version:    appleDoubleVersion,
+		Filler:     appleDoubleFiller,
+		NumEntries: 2,
+		Entries: [2]appleDoubleEntry{
+			{ID: appleDoubleEntryFinderInfo, Offset: appleDoubleFinderInfoOffset, Length: totalSize - appleDoubleFinderInfoOffset},
+			{ID: appleDoubleEntryResourceFork, Offset: totalSize, Length: uint32(len(resourceFork))},
+		},
+	}
+	copy(header.FinderInfo[:], attrs[xattrFinderInfo])
+
+	var out bytes.Buffer
+	_ = binary.Write(&out, binary.BigEndian, header)
+	_ = binary.Write(&out, binary.BigEndian, attrHeader{
+		Magic:      attrHeaderMagic,
+		TotalSize:  totalSize,
+		DataStart:  dataStart,
+		DataLength: uint32(data.Len()),
+		NumAttrs:   uint16(len(names)),
+	})
+
+	_, _ = entries.WriteTo(&out)
+	_, _ = data.WriteTo(&out)
+	out.Write(make([]byte, int(totalSize)-out.Len()))
+	out.Write(resourceFork)
+
+	return out.Bytes()
+}
+
+// attrEntrySize returns the size of an attribute
+// entry for the named attribute, padded to a
+// four byte boundary.
+func attrEntrySize(name string) uint32 {
+	size := uint32(4 + 4 + 2 + 1 + len(name) + 1)
+	return (size + 3) &^ 3
+}
@@ -0,0 +1,231 @@
+package archive
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// appleDoublePrefix is the directory ditto
+	// places AppleDouble files under so that
+	// they can be ignored by other ZIP tools.
+	appleDoublePrefix = "__MACOSX"
+)
+
+// Writer creates ZIP archives in the same way as
+// `ditto -c -k --sequesterRsrc --keepParent`, that
+// is the layout Apple recommends for submissions
+// to the Notary service.
+//
+// Each entry carries its Unix mode in the external
+// attributes, symlinks are stored as links rather
+// than being followed, directories have their own
+// entries, and extended attributes (including the
+// Finder info and resource fork) are stored in an
+// AppleDouble file under the __MACOSX directory.
+type Writer struct {
+	zip *zip.Writer
+
+	reproducible bool
+	modTime      time.Time
+}
+
+// Option applies an optional setting
+// to a Writer when it is created.
+type Option func(writer *Writer)
+
+// WithReproducible makes the Writer produce the same
+// archive, byte for byte, for the same input tree by
+// recording modTime as the modification time of every
+// entry and pinning the compression level used.
+func WithReproducible(modTime time.Time) Option {
+	return func(writer *Writer) {
+		writer.reproducible = true
+		writer.modTime = modTime.UTC()
+	}
+}
+
+// NewWriter creates a Writer that writes
+// the ZIP archive to the supplied writer.
+func NewWriter(w io.Writer, opts ...Option) *Writer {
+	writer := &Writer{zip: zip.NewWriter(w)}
+	for _, opt := range opts {
+		opt(writer)
+	}
+
+	if writer.reproducible {
+		// Avoid depending on the default compressor
+		// registered for the process, which may be
+		// replaced by other packages
+		writer.zip.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, flate.DefaultCompression)
+		})
+	}
+
+	return writer
+}
+
+// Close finishes writing the ZIP archive, it
+// does not close the underlying writer.
+func (writer *Writer) Close() error {
+	return writer.zip.Close()
+}
+
+// AddTree adds the file or directory at root to the
+// archive, entries are named relative to the parent
+// of root so that the archive extracts to a single
+// file or directory with the same name as root.
+//
+// Entries are added in lexical order, as guaranteed
+// by filepath.Walk, so the order of entries doesn't
+// depend on the order the file system returns them.
+func (writer *Writer) AddTree(root string) error {
+	parent := filepath.Dir(root)
+
+	return filepath.Walk(root, func(filePath string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(parent, filePath)
+		if err != nil {
+			return fmt.Errorf("determine name of entry: %w", err)
+		}
+
+		if err = writer.addEntry(filePath, filepath.ToSlash(name), info); err != nil {
+			return fmt.Errorf("add '%s' to archive: %w", name, err)
+		}
+
+		return nil
+	})
+}
+
+func (writer *Writer) addEntry(filePath, name string, info fs.FileInfo) error {
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: writer.entryModTime(info),
+	}
+	header.SetMode(info.Mode())
+
+	var err error
+	switch {
+	case info.IsDir():
+		header.Name += "/"
+		header.Method = zip.Store
+		_, err = writer.zip.CreateHeader(header)
+
+	case info.Mode()&fs.ModeSymlink != 0:
+		err = writer.addSymlink(filePath, header)
+
+	case info.Mode().IsRegular():
+		err = writer.addFile(filePath, header)
+
+	default:
+		// Sockets, devices and pipes can't be
+		// represented in a ZIP so are skipped
+		// just like ditto does
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return writer.addAppleDouble(filePath, name, info)
+}
+
+func (writer *Writer) addSymlink(filePath string, header *zip.FileHeader) error {
+	target, err := os.Readlink(filePath)
+	if err != nil {
+		return fmt.Errorf("read symlink target: %w", err)
+	}
+
+	header.Method = zip.Store
+	entry, err := writer.zip.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("create ZIP entry for symlink: %w", err)
+	}
+
+	if _, err = io.WriteString(entry, target); err != nil {
+		return fmt.Errorf("write symlink target: %w", err)
+	}
+
+	return nil
+}
+
+func (writer *Writer) addFile(filePath string, header *zip.FileHeader) error {
+	src, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer src.Close()
+
+	entry, err := writer.zip.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("create ZIP entry for file: %w", err)
+	}
+
+	if _, err = io.Copy(entry, src); err != nil {
+		return fmt.Errorf("write file contents: %w", err)
+	}
+
+	return nil
+}
+
+// addAppleDouble adds an AppleDouble sidecar entry for
+// the file at filePath if, and only if, the file has
+// extended attributes that need to be preserved.
+func (writer *Writer) addAppleDouble(filePath, name string, info fs.FileInfo) error {
+	if info.Mode()&fs.ModeSymlink != 0 {
+		return nil
+	}
+
+	attrs, err := readExtendedAttributes(filePath)
+	if err != nil {
+		return fmt.Errorf("read extended attributes: %w", err)
+	} else if len(attrs) == 0 {
+		return nil
+	}
+
+	header := &zip.FileHeader{
+		Name:     appleDoubleName(name),
+		Method:   zip.Deflate,
+		Modified: writer.entryModTime(info),
+	}
+	header.SetMode(0644)
+
+	entry, err := writer.zip.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("create ZIP entry for AppleDouble file: %w", err)
+	}
+
+	if _, err = entry.Write(encodeAppleDouble(attrs)); err != nil {
+		return fmt.Errorf("write AppleDouble file: %w", err)
+	}
+
+	return nil
+}
+
+func (writer *Writer) entryModTime(info fs.FileInfo) time.Time {
+	if writer.reproducible {
+		return writer.modTime
+	}
+
+	return info.ModTime()
+}
+
+// appleDoubleName returns the name of the AppleDouble
+// entry for an entry, for example 'Foo.app/Contents'
+// becomes '__MACOSX/Foo.app/._Contents'.
+func appleDoubleName(name string) string {
+	dir, base := path.Split(name)
+	return path.Join(appleDoublePrefix, dir, "._"+base)
+}
@@ -0,0 +1,700 @@
+//go:build darwin || linux
+
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// creatorUnix is the "version made by" host
+// system ditto records for every entry.
+const creatorUnix = 3
+
+// parsedAppleDouble is an AppleDouble file decoded
+// by parseAppleDouble, independently of the structs
+// used by encodeAppleDouble.
+type parsedAppleDouble struct {
+	finderInfo   []byte
+	resourceFork []byte
+	attrs        map[string][]byte
+}
+
+// parseAppleDouble decodes an AppleDouble file in the
+// layout written by copyfile(3), checking the offsets
+// and alignment of every part as it goes.
+func parseAppleDouble(data []byte) (*parsedAppleDouble, error) {
+	if len(data) < 84+attrHeaderSize {
+		return nil, fmt.Errorf("file is %d bytes, shorter than the headers", len(data))
+	}
+
+	be := binary.BigEndian
+	switch {
+	case be.Uint32(data[0:]) != appleDoubleMagic:
+		return nil, fmt.Errorf("bad magic %#x", be.Uint32(data[0:]))
+
+	case be.Uint32(data[4:]) != appleDoubleVersion:
+		return nil, fmt.Errorf("bad version %#x", be.Uint32(data[4:]))
+
+	case !bytes.Equal(data[8:24], []byte("Mac OS X        ")):
+		return nil, fmt.Errorf("bad filler %q", data[8:24])
+
+	case be.Uint16(data[24:]) != 2:
+		return nil, fmt.Errorf("expected 2 entries, got %d", be.Uint16(data[24:]))
+	}
+
+	finderId, finderOffset, finderLength := be.Uint32(data[26:]), be.Uint32(data[30:]), be.Uint32(data[34:])
+	rsrcId, rsrcOffset, rsrcLength := be.Uint32(data[38:]), be.Uint32(data[42:]), be.Uint32(data[46:])
+
+	// The Finder info entry covers the Finder info,
+	// its padding, and the whole extended attribute
+	// area that follows it
+	attrs := data[84:]
+	totalSize := be.Uint32(attrs[8:])
+	switch {
+	case finderId != appleDoubleEntryFinderInfo || finderOffset != 50:
+		return nil, fmt.Errorf("bad Finder info entry %d at %d", finderId, finderOffset)
+
+	case rsrcId != appleDoubleEntryResourceFork:
+		return nil, fmt.Errorf("bad resource fork entry %d", rsrcId)
+
+	case !bytes.Equal(data[82:84], []byte{0, 0}):
+		return nil, errors.New("Finder info padding isn't zeroed")
+
+	case be.Uint32(attrs[0:]) != attrHeaderMagic:
+		return nil, fmt.Errorf("bad ATTR magic %#x", be.Uint32(attrs[0:]))
+
+	case finderLength != totalSize-finderOffset:
+		return nil, fmt.Errorf("Finder info length %d doesn't reach the end of the attributes at %d", finderLength, totalSize)
+
+	case rsrcOffset != totalSize || int(rsrcOffset+rsrcLength) != len(data):
+		return nil, fmt.Errorf("resource fork at %d+%d doesn't follow the attributes and end the file", rsrcOffset, rsrcLength)
+
+	case len(data) < appleDoubleMinSize && int(rsrcLength) < appleDoubleMinSize:
+		return nil, fmt.Errorf("file is %d bytes, not padded to %d", len(data), appleDoubleMinSize)
+
+	case !bytes.Equal(attrs[20:32], make([]byte, 12)) || be.Uint16(attrs[32:]) != 0:
+		return nil, errors.New("ATTR reserved space or flags aren't zeroed")
+	}
+
+	parsed := &parsedAppleDouble{
+		finderInfo:   data[50:82],
+		resourceFork: data[rsrcOffset:],
+		attrs:        make(map[string][]byte),
+	}
+
+	// A file without a resource fork is given an empty one,
+	// which is recognised by its tag as copyfile(3) does
+	if bytes.Contains(parsed.resourceFork, []byte("This resource fork intentionally left blank")) {
+		if !bytes.Equal(parsed.resourceFork, blankResourceFork) {
+			return nil, fmt.Errorf("empty resource fork doesn't match: % x", parsed.resourceFork)
+		}
+
+		parsed.resourceFork = nil
+	}
+
+	dataStart, dataLength := be.Uint32(attrs[12:]), be.Uint32(attrs[16:])
+	if dataStart+dataLength > totalSize {
+		return nil, fmt.Errorf("data area %d+%d extends past total size %d", dataStart, dataLength, totalSize)
+	} else if !bytes.Equal(data[dataStart+dataLength:totalSize], make([]byte, totalSize-dataStart-dataLength)) {
+		return nil, errors.New("padding after the data area isn't zeroed")
+	}
+
+	offset := uint32(84 + attrHeaderSize)
+	for i := 0; i < int(be.Uint16(attrs[34:])); i++ {
+		if offset%4 != 0 {
+			return nil, fmt.Errorf("attribute entry %d at %d isn't 4 byte aligned", i, offset)
+		}
+
+		entry := data[offset:]
+		valueOffset, valueLength, nameLen := be.Uint32(entry[0:]), be.Uint32(entry[4:]), uint32(entry[10])
+		name := entry[11 : 11+nameLen]
+		if name[nameLen-1] != 0 {
+			return nil, fmt.Errorf("attribute name %q isn't NUL terminated", name)
+		} else if valueOffset < dataStart || valueOffset+valueLength > dataStart+dataLength {
+			return nil, fmt.Errorf("attribute %q at %d+%d is outside the data area", name, valueOffset, valueLength)
+		}
+
+		parsed.attrs[string(name[:nameLen-1])] = data[valueOffset : valueOffset+valueLength]
+		offset += attrEntrySize(string(name[:nameLen-1]))
+	}
+
+	if offset != dataStart {
+		return nil, fmt.Errorf("attribute entries end at %d, data starts at %d", offset, dataStart)
+	}
+
+	return parsed, nil
+}
+
+func TestAttrEntrySize(t *testing.T) {
+	tests := map[string]uint32{
+		"a":                      16, // 11 + 2 = 13, padded to 16
+		"abcd":                   16, // 11 + 5 = 16
+		"abcde":                  20, // 11 + 6 = 17, padded to 20
+		"com.apple.quarantine":   32, // 11 + 21 = 32
+		"com.apple.lastuseddate": 36, // 11 + 23 = 34, padded to 36
+	}
+
+	for name, expected := range tests {
+		if size := attrEntrySize(name); size != expected {
+			t.Errorf("attrEntrySize(%q) = %d, expected %d", name, size, expected)
+		}
+	}
+}
+
+// blankResourceFork is the empty resource fork copyfile(3)
+// writes to the AppleDouble file of a file without one.
+var blankResourceFork = func() []byte {
+	fork := []byte{
+		0x00, 0x00, 0x01, 0x00, // data offset
+		0x00, 0x00, 0x01, 0x00, // map offset
+		0x00, 0x00, 0x00, 0x00, // data length
+		0x00, 0x00, 0x00, 0x1e, // map length
+	}
+	fork = append(fork, "This resource fork intentionally left blank   \x00"...)
+	fork = append(fork, make([]byte, 112-47+128)...) // rest of the system data and the application data
+
+	// The map, a copy of the header followed by the
+	// next map, file reference, and attributes, then
+	// the offsets of the empty type and name lists
+	fork = append(fork,
+		0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1e,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x1c, // type list offset
+		0x00, 0x1e, // name list offset
+		0xff, 0xff, // number of types minus one
+	)
+
+	return fork
+}()
+
+func TestEncodeAppleDoubleHeader(t *testing.T) {
+	encoded := encodeAppleDouble(map[string][]byte{"com.apple.quarantine": []byte("0081;00000000;Safari;")})
+
+	// The fixed 84 byte AppleDouble header,
+	// with all fields big-endian
+	expected := []byte{
+		0x00, 0x05, 0x16, 0x07, // magic
+		0x00, 0x02, 0x00, 0x00, // version
+		'M', 'a', 'c', ' ', 'O', 'S', ' ', 'X', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ',
+		0x00, 0x02, // number of entries
+		0x00, 0x00, 0x00, 0x09, 0x00, 0x00, 0x00, 0x32, 0x00, 0x00, 0x0e, 0xb0, // Finder info, 50+3760
+		0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x0e, 0xe2, 0x00, 0x00, 0x01, 0x1e, // resource fork, 3810+286
+	}
+	expected = append(expected, make([]byte, 32+2)...) // Finder info and padding
+
+	// The ATTR header, one 32 byte entry and the value,
+	// 84 + 36 + 32 + 21 = 173 bytes, padded so that the
+	// 286 byte empty resource fork ends at 4096 bytes
+	expected = append(expected,
+		'A', 'T', 'T', 'R',
+		0x00, 0x00, 0x00, 0x00, // debug tag
+		0x00, 0x00, 0x0e, 0xe2, // total size
+		0x00, 0x00, 0x00, 0x98, // data start
+		0x00, 0x00, 0x00, 0x15, // data length
+	)
+	expected = append(expected, make([]byte, 12)...) // reserved
+	expected = append(expected,
+		0x00, 0x00, // flags
+		0x00, 0x01, // number of attributes
+		0x00, 0x00, 0x00, 0x98, 0x00, 0x00, 0x00, 0x15, 0x00, 0x00, 0x15,
+	)
+	expected = append(expected, "com.apple.quarantine\x00"...)
+	expected = append(expected, "0081;00000000;Safari;"...)
+	expected = append(expected, make([]byte, 3810-173)...)
+	expected = append(expected, blankResourceFork...)
+
+	if len(encoded) != 4096 {
+		t.Errorf("encoded AppleDouble is %d bytes, expected 4096", len(encoded))
+	}
+
+	if !bytes.Equal(encoded, expected) {
+		t.Errorf("encoded AppleDouble doesn't match\n got: % x\nwant: % x", encoded, expected)
+	}
+}
+
+func TestEncodeAppleDoubleLayout(t *testing.T) {
+	finderInfo := []byte("APPLmacs\x01\x00")
+	attrs := map[string][]byte{
+		xattrFinderInfo:          finderInfo,
+		xattrResourceFork:        []byte("resource fork data"),
+		"com.apple.quarantine":   []byte("0081;00000000;Safari;"),
+		"com.apple.lastuseddate": make([]byte, 16),
+		"a":                      {},
+		"abcde":                  []byte("value"),
+	}
+
+	encoded := encodeAppleDouble(attrs)
+	parsed, err := parseAppleDouble(encoded)
+	if err != nil {
+		t.Fatalf("parse encoded AppleDouble: %v", err)
+	}
+
+	if !bytes.Equal(parsed.finderInfo, append(finderInfo, make([]byte, 32-len(finderInfo))...)) {
+		t.Errorf("Finder info isn't zero padded to 32 bytes: % x", parsed.finderInfo)
+	}
+
+	if !bytes.Equal(parsed.resourceFork, attrs[xattrResourceFork]) {
+		t.Errorf("resource fork = %q, expected %q", parsed.resourceFork, attrs[xattrResourceFork])
+	}
+
+	delete(attrs, xattrFinderInfo)
+	delete(attrs, xattrResourceFork)
+	if !maps.EqualFunc(parsed.attrs, attrs, bytes.Equal) {
+		t.Errorf("attributes = %q, expected %q", parsed.attrs, attrs)
+	}
+
+	if !bytes.Equal(encodeAppleDouble(attrs), encodeAppleDouble(maps.Clone(attrs))) {
+		t.Error("encoding the same attributes twice produced different output")
+	}
+}
+
+func TestEncodeAppleDoubleSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		attrs    map[string][]byte
+		size     int
+		rsrcFork int
+	}{
+		{
+			name:     "padded with an empty resource fork",
+			attrs:    map[string][]byte{"com.example": []byte("value")},
+			size:     4096,
+			rsrcFork: 3810,
+		},
+		{
+			name:     "padded with a resource fork",
+			attrs:    map[string][]byte{xattrResourceFork: []byte("resource fork data")},
+			size:     4096,
+			rsrcFork: 4096 - 18,
+		},
+		{
+			name:     "attributes larger than the padding",
+			attrs:    map[string][]byte{"com.example": make([]byte, 4000)},
+			size:     84 + 36 + 24 + 4000 + 286,
+			rsrcFork: 84 + 36 + 24 + 4000,
+		},
+		{
+			name:     "resource fork larger than the padding",
+			attrs:    map[string][]byte{xattrResourceFork: make([]byte, 5000)},
+			size:     84 + 36 + 5000,
+			rsrcFork: 84 + 36,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encoded := encodeAppleDouble(test.attrs)
+			if _, err := parseAppleDouble(encoded); err != nil {
+				t.Fatalf("parse encoded AppleDouble: %v", err)
+			}
+
+			if len(encoded) != test.size {
+				t.Errorf("encoded AppleDouble is %d bytes, expected %d", len(encoded), test.size)
+			}
+
+			if offset := binary.BigEndian.Uint32(encoded[42:]); int(offset) != test.rsrcFork {
+				t.Errorf("resource fork is at %d, expected %d", offset, test.rsrcFork)
+			}
+		})
+	}
+}
+
+// setExtendedAttribute sets the attribute with
+// its macOS name on path, skipping the test if
+// the file system doesn't support them.
+func setExtendedAttribute(t *testing.T, path, name string, value []byte) {
+	t.Helper()
+
+	if runtime.GOOS == "linux" {
+		name = "user." + name
+	}
+
+	if err := unix.Lsetxattr(path, name, value, 0); errors.Is(err, unix.ENOTSUP) {
+		t.Skipf("extended attributes aren't supported by the file system: %v", err)
+	} else if err != nil {
+		t.Fatalf("set extended attribute '%s' on '%s': %v", name, path, err)
+	}
+}
+
+// archiveEntry is the part of a ZIP entry
+// compared against the reference archives.
+type archiveEntry struct {
+	mode    fs.FileMode
+	attrs   uint32
+	creator uint16
+	content []byte
+}
+
+func readArchive(t *testing.T, data []byte) map[string]archiveEntry {
+	t.Helper()
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open ZIP: %v", err)
+	}
+
+	entries := make(map[string]archiveEntry, len(reader.File))
+	for _, file := range reader.File {
+		entry := archiveEntry{
+			mode:    file.Mode(),
+			attrs:   file.ExternalAttrs,
+			creator: file.CreatorVersion >> 8,
+		}
+
+		if !file.Mode().IsDir() {
+			src, err := file.Open()
+			if err != nil {
+				t.Fatalf("open entry '%s': %v", file.Name, err)
+			}
+
+			entry.content, err = io.ReadAll(src)
+			_ = src.Close()
+			if err != nil {
+				t.Fatalf("read entry '%s': %v", file.Name, err)
+			}
+		}
+
+		entries[file.Name] = entry
+	}
+
+	return entries
+}
+
+func sortedNames(entries map[string]archiveEntry) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+	return names
+}
+
+func archiveTree(t *testing.T, root string, opts ...Option) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf, opts...)
+	if err := writer.AddTree(root); err != nil {
+		t.Fatalf("add tree to archive: %v", err)
+	} else if err = writer.Close(); err != nil {
+		t.Fatalf("close archive: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestWriterAddTree(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "Example.app")
+	for _, dir := range []string{"Contents/MacOS", "Contents/Resources"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatalf("create '%s': %v", dir, err)
+		}
+	}
+
+	files := map[string]fs.FileMode{
+		"Contents/Info.plist":         0644,
+		"Contents/MacOS/example":      0755,
+		"Contents/Resources/readonly": 0444,
+	}
+
+	for name, mode := range files {
+		file := filepath.Join(root, name)
+		if err := os.WriteFile(file, []byte(name), 0644); err != nil {
+			t.Fatalf("write '%s': %v", name, err)
+		}
+
+		// Attributes are set before the mode so that
+		// a read only file can still be written to
+		if name == "Contents/Info.plist" {
+			setExtendedAttribute(t, file, "com.apple.quarantine", []byte("0081;00000000;Safari;"))
+			setExtendedAttribute(t, file, xattrFinderInfo, []byte("TEXTttxt"))
+		}
+
+		if err := os.Chmod(file, mode); err != nil {
+			t.Fatalf("chmod '%s': %v", name, err)
+		}
+	}
+
+	link := filepath.Join(root, "Contents/Current")
+	if err := os.Symlink("MacOS/example", link); err != nil {
+		t.Fatalf("create symlink: %v", err)
+	}
+
+	linkInfo, err := os.Lstat(link)
+	if err != nil {
+		t.Fatalf("stat symlink: %v", err)
+	}
+
+	entries := readArchive(t, archiveTree(t, root))
+
+	expected := []string{
+		"Example.app/",
+		"Example.app/Contents/",
+		"Example.app/Contents/Current",
+		"Example.app/Contents/Info.plist",
+		"Example.app/Contents/MacOS/",
+		"Example.app/Contents/MacOS/example",
+		"Example.app/Contents/Resources/",
+		"Example.app/Contents/Resources/readonly",
+		"__MACOSX/Example.app/Contents/._Info.plist",
+	}
+
+	if names := sortedNames(entries); !slices.Equal(names, expected) {
+		t.Fatalf("entries = %q, expected %q", names, expected)
+	}
+
+	for name, entry := range entries {
+		if entry.creator != creatorUnix {
+			t.Errorf("entry '%s' was made by host %d, expected Unix", name, entry.creator)
+		}
+
+		var expectedMode fs.FileMode
+		switch mode, isFile := files[strings.TrimPrefix(name, "Example.app/")]; {
+		case isFile:
+			expectedMode = mode
+
+		case strings.HasSuffix(name, "/"):
+			// Directories also set the MS-DOS
+			// directory bit, as ditto does
+			expectedMode = fs.ModeDir | 0755
+			if entry.attrs&0x10 == 0 {
+				t.Errorf("directory '%s' doesn't have the MS-DOS directory attribute", name)
+			}
+
+		case name == "Example.app/Contents/Current":
+			expectedMode = linkInfo.Mode()
+			if string(entry.content) != "MacOS/example" {
+				t.Errorf("symlink target = %q, expected %q", entry.content, "MacOS/example")
+			}
+
+		default:
+			expectedMode = 0644
+		}
+
+		if entry.mode != expectedMode {
+			t.Errorf("entry '%s' has mode %v, expected %v", name, entry.mode, expectedMode)
+		} else if unixMode := entry.attrs >> 16; unixMode&0777 != uint32(expectedMode.Perm()) {
+			t.Errorf("entry '%s' has Unix mode %o in its external attributes", name, unixMode)
+		}
+	}
+
+	parsed, err := parseAppleDouble(entries["__MACOSX/Example.app/Contents/._Info.plist"].content)
+	if err != nil {
+		t.Fatalf("parse AppleDouble entry: %v", err)
+	}
+
+	if string(parsed.attrs["com.apple.quarantine"]) != "0081;00000000;Safari;" {
+		t.Errorf("quarantine attribute = %q", parsed.attrs["com.apple.quarantine"])
+	} else if !bytes.HasPrefix(parsed.finderInfo, []byte("TEXTttxt")) {
+		t.Errorf("Finder info = %q", parsed.finderInfo)
+	}
+}
+
+func TestWriterReproducible(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "Example.app")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatalf("create bundle: %v", err)
+	} else if err = os.WriteFile(filepath.Join(root, "file"), []byte("contents"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	modTime := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	first := archiveTree(t, root, WithReproducible(modTime))
+
+	if err := os.Chtimes(filepath.Join(root, "file"), modTime.AddDate(1, 0, 0), modTime.AddDate(1, 0, 0)); err != nil {
+		t.Fatalf("change file times: %v", err)
+	}
+
+	if second := archiveTree(t, root, WithReproducible(modTime)); !bytes.Equal(first, second) {
+		t.Error("archiving the same contents twice produced different archives")
+	}
+}
+
+// TestReferenceArchives compares the archives produced by the
+// Writer against those produced by ditto, as described in
+// testdata/README.md. The tree of each reference archive is
+// recreated, including the extended attributes stored in its
+// AppleDouble files, then archived again and the entries of
+// the two archives compared.
+//
+// The references must between them cover an executable, a
+// symlink, a quarantine attribute, and a resource fork, the
+// test fails rather than passing without comparing them.
+func TestReferenceArchives(t *testing.T) {
+	references, err := filepath.Glob(filepath.Join("testdata", "*.zip"))
+	if err != nil {
+		t.Fatalf("find reference archives: %v", err)
+	} else if len(references) == 0 {
+		t.Fatal("no reference archives in testdata, see testdata/README.md")
+	}
+
+	covered := make(map[string]bool)
+	defer func() {
+		for _, feature := range []string{"executable", "symlink", "quarantine attribute", "resource fork"} {
+			if !covered[feature] {
+				t.Errorf("no reference archive contains a %s, see testdata/README.md", feature)
+			}
+		}
+	}()
+
+	for _, reference := range references {
+		t.Run(filepath.Base(reference), func(t *testing.T) {
+			data, err := os.ReadFile(reference)
+			if err != nil {
+				t.Fatalf("read reference archive: %v", err)
+			}
+
+			expected := readArchive(t, data)
+			for feature := range referenceFeatures(t, expected) {
+				covered[feature] = true
+			}
+
+			root := extractReference(t, expected)
+			actual := readArchive(t, archiveTree(t, root))
+
+			expectedNames, actualNames := sortedNames(expected), sortedNames(actual)
+			if !slices.Equal(expectedNames, actualNames) {
+				t.Fatalf("entries = %q, expected %q", actualNames, expectedNames)
+			}
+
+			for _, name := range expectedNames {
+				want, got := expected[name], actual[name]
+				switch {
+				case got.mode != want.mode:
+					t.Errorf("entry '%s' has mode %v, expected %v", name, got.mode, want.mode)
+
+				case got.attrs != want.attrs:
+					t.Errorf("entry '%s' has external attributes %#x, expected %#x", name, got.attrs, want.attrs)
+
+				case got.creator != want.creator:
+					t.Errorf("entry '%s' was made by host %d, expected %d", name, got.creator, want.creator)
+
+				case (got.mode&fs.ModeSymlink != 0 || strings.HasPrefix(name, appleDoublePrefix+"/")) && !bytes.Equal(got.content, want.content):
+					t.Errorf("entry '%s' doesn't match\n got: % x\nwant: % x", name, got.content, want.content)
+				}
+			}
+		})
+	}
+}
+
+// referenceFeatures returns the features, of those
+// TestReferenceArchives requires, that the entries of
+// a reference archive contain.
+func referenceFeatures(t *testing.T, entries map[string]archiveEntry) map[string]bool {
+	t.Helper()
+
+	features := make(map[string]bool)
+	for name, entry := range entries {
+		switch {
+		case entry.mode&fs.ModeSymlink != 0:
+			features["symlink"] = true
+
+		case strings.HasPrefix(name, appleDoublePrefix+"/"):
+			parsed, err := parseAppleDouble(entry.content)
+			if err != nil {
+				t.Fatalf("parse AppleDouble entry '%s': %v", name, err)
+			}
+
+			_, quarantined := parsed.attrs["com.apple.quarantine"]
+			features["quarantine attribute"] = features["quarantine attribute"] || quarantined
+			features["resource fork"] = features["resource fork"] || len(parsed.resourceFork) > 0
+
+		case entry.mode.IsRegular() && entry.mode.Perm()&0111 != 0:
+			features["executable"] = true
+		}
+	}
+
+	return features
+}
+
+// extractReference recreates the tree stored in a reference
+// archive, returning the path of its top level entry.
+func extractReference(t *testing.T, entries map[string]archiveEntry) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	names := sortedNames(entries)
+
+	var root string
+	for _, name := range names {
+		if strings.HasPrefix(name, appleDoublePrefix+"/") {
+			continue
+		}
+
+		entry, target := entries[name], filepath.Join(dir, filepath.FromSlash(name))
+		if top, _, _ := strings.Cut(name, "/"); len(root) == 0 {
+			root = filepath.Join(dir, top)
+		}
+
+		var err error
+		switch {
+		case entry.mode.IsDir():
+			err = os.MkdirAll(target, 0755)
+
+		case entry.mode&fs.ModeSymlink != 0:
+			err = os.Symlink(string(entry.content), target)
+
+		default:
+			err = os.WriteFile(target, entry.content, 0644)
+		}
+
+		if err != nil {
+			t.Fatalf("extract '%s': %v", name, err)
+		}
+	}
+
+	// Extended attributes are set before the modes
+	// so that read only files can still be written
+	for _, name := range names {
+		parent, base := path.Split(strings.TrimPrefix(name, appleDoublePrefix+"/"))
+		if !strings.HasPrefix(name, appleDoublePrefix+"/") || !strings.HasPrefix(base, "._") {
+			continue
+		}
+
+		parsed, err := parseAppleDouble(entries[name].content)
+		if err != nil {
+			t.Fatalf("parse AppleDouble entry '%s': %v", name, err)
+		}
+
+		target := filepath.Join(filepath.Dir(root), filepath.FromSlash(parent), strings.TrimPrefix(base, "._"))
+		if !bytes.Equal(parsed.finderInfo, make([]byte, 32)) {
+			setExtendedAttribute(t, target, xattrFinderInfo, parsed.finderInfo)
+		}
+
+		if len(parsed.resourceFork) > 0 {
+			setExtendedAttribute(t, target, xattrResourceFork, parsed.resourceFork)
+		}
+
+		for attr, value := range parsed.attrs {
+			setExtendedAttribute(t, target, attr, value)
+		}
+	}
+
+	for _, name := range names {
+		if entry := entries[name]; !strings.HasPrefix(name, appleDoublePrefix+"/") && entry.mode&fs.ModeSymlink == 0 {
+			if err := os.Chmod(filepath.Join(dir, filepath.FromSlash(name)), entry.mode.Perm()); err != nil {
+				t.Fatalf("chmod '%s': %v", name, err)
+			}
+		}
+	}
+
+	return root
+}
@@ -0,0 +1,5 @@
+package archive
+
+func toMacAttributeName(name string) (string, bool) {
+	return name, true
+}
@@ -0,0 +1,10 @@
+package archive
+
+import "strings"
+
+// toMacAttributeName maps a Linux extended attribute
+// into the flat macOS namespace, only attributes in
+// the user namespace can be represented on macOS.
+func toMacAttributeName(name string) (string, bool) {
+	return strings.CutPrefix(name, "user.")
+}
@@ -0,0 +1,9 @@
+//go:build !darwin && !linux
+
+package archive
+
+// readExtendedAttributes is a no-op on platforms
+// where extended attributes aren't supported.
+func readExtendedAttributes(_ string) (map[string][]byte, error) {
+	return nil, nil
+}
@@ -0,0 +1,80 @@
+//go:build darwin || linux
+
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// readExtendedAttributes returns the extended attributes
+// of the file at path, without following symlinks, keyed
+// by the name macOS would give the attribute.
+func readExtendedAttributes(path string) (map[string][]byte, error) {
+	names, err := listExtendedAttributes(path)
+	if err != nil {
+		return nil, fmt.Errorf("list extended attributes: %w", err)
+	}
+
+	attrs := make(map[string][]byte, len(names))
+	for _, name := range names {
+		macName, supported := toMacAttributeName(name)
+		if !supported {
+			continue
+		}
+
+		value, err := getExtendedAttribute(path, name)
+		if err != nil {
+			return nil, fmt.Errorf("get extended attribute '%s': %w", name, err)
+		}
+
+		attrs[macName] = value
+	}
+
+	return attrs, nil
+}
+
+func listExtendedAttributes(path string) ([]string, error) {
+	size, err := unix.Llistxattr(path, nil)
+	switch {
+	case errors.Is(err, unix.ENOTSUP):
+		return nil, nil
+
+	case err != nil:
+		return nil, err
+
+	case size == 0:
+		return nil, nil
+	}
+
+	buf := make([]byte, size)
+	if size, err = unix.Llistxattr(path, buf); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+
+	return names, nil
+}
+
+func getExtendedAttribute(path, name string) ([]byte, error) {
+	size, err := unix.Lgetxattr(path, name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, size)
+	if size, err = unix.Lgetxattr(path, name, buf); err != nil {
+		return nil, err
+	}
+
+	return buf[:size], nil
+}
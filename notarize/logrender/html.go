@@ -0,0 +1,70 @@
+package logrender
+
+import (
+	"html/template"
+	"io"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+)
+
+var htmlTemplate = template.Must(template.New("log").Funcs(template.FuncMap{
+	"orDefault": orDefault,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Notarization log for {{ orDefault .Log.ArchiveFilename "unknown archive" }}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Helvetica Neue", sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+code { font-family: Menlo, monospace; }
+.severity-error { color: #b00020; font-weight: bold; }
+.severity-warning { color: #a86500; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Notarization log for <code>{{ orDefault .Log.ArchiveFilename "unknown archive" }}</code></h1>
+<table>
+<tr><th>Submission</th><td><code>{{ .Log.JobID }}</code></td></tr>
+<tr><th>Status</th><td>{{ .Log.Status }} ({{ .Log.StatusSummary }})</td></tr>
+<tr><th>Uploaded</th><td>{{ .Log.UploadDate }}</td></tr>
+<tr><th>SHA-256</th><td><code>{{ .Log.SHA256 }}</code></td></tr>
+</table>
+<h2>Issues ({{ len .Log.Issues }})</h2>
+{{- range .Groups }}
+<h3><code>{{ orDefault .Path "(no path)" }}</code> ({{ orDefault .Architecture "all architectures" }})</h3>
+<ul>
+{{- range .Issues }}
+<li><span class="severity-{{ .Severity }}">{{ .Severity }}</span>{{ if .Code }} <code>{{ .Code }}</code>{{ end }}: {{ .Message }}{{ if .DocUrl }} (<a href="{{ .DocUrl }}">documentation</a>){{ end }}</li>
+{{- end }}
+</ul>
+{{- else }}
+<p>None</p>
+{{- end }}
+<h2>Ticket contents ({{ len .Tickets }})</h2>
+{{- if .Tickets }}
+<table>
+<tr><th>Path</th><th>Architecture</th><th>Digest</th><th>CDHash</th></tr>
+{{- range .Tickets }}
+<tr><td><code>{{ .Path }}</code></td><td>{{ orDefault .Arch "-" }}</td><td>{{ .DigestAlgorithm }}</td><td><code>{{ .CDHash }}</code></td></tr>
+{{- end }}
+</table>
+{{- else }}
+<p>None</p>
+{{- end }}
+</body>
+</html>
+`))
+
+func renderHTML(w io.Writer, log *api.NotarizationLog) error {
+	return htmlTemplate.Execute(w, struct {
+		Log     *api.NotarizationLog
+		Groups  []issueGroup
+		Tickets []api.NotarizationTicket
+	}{
+		Log:     log,
+		Groups:  groupIssues(log.Issues),
+		Tickets: sortedTickets(log.TicketContents),
+	})
+}
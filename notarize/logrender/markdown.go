@@ -0,0 +1,78 @@
+package logrender
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+)
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`,
+	"[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`,
+	"|", `\|`, "\n", " ",
+)
+
+func renderMarkdown(w io.Writer, log *api.NotarizationLog) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "### Notarization log for %s\n\n", markdownCode(orDefault(log.ArchiveFilename, "unknown archive")))
+	fmt.Fprintf(&b, "| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| **Submission** | %s |\n", markdownCode(log.JobID))
+	fmt.Fprintf(&b, "| **Status** | %s (%s) |\n", markdownEscaper.Replace(log.Status), markdownEscaper.Replace(log.StatusSummary))
+	fmt.Fprintf(&b, "| **Uploaded** | %s |\n", markdownEscaper.Replace(log.UploadDate))
+	fmt.Fprintf(&b, "| **SHA-256** | %s |\n", markdownCode(log.SHA256))
+
+	fmt.Fprintf(&b, "\n#### Issues (%d)\n", len(log.Issues))
+	groups := groupIssues(log.Issues)
+	if len(groups) == 0 {
+		b.WriteString("\nNone\n")
+	}
+
+	for _, group := range groups {
+		fmt.Fprintf(&b, "\n%s (%s)\n\n", markdownCode(orDefault(group.Path, "(no path)")), markdownEscaper.Replace(orDefault(group.Architecture, "all architectures")))
+		for _, issue := range group.Issues {
+			fmt.Fprintf(&b, "- **%s**", markdownEscaper.Replace(issue.Severity))
+			if len(issue.Code) > 0 {
+				fmt.Fprintf(&b, " %s", markdownCode(issue.Code))
+			}
+
+			fmt.Fprintf(&b, ": %s", markdownEscaper.Replace(issue.Message))
+			if len(issue.DocUrl) > 0 {
+				fmt.Fprintf(&b, " ([documentation](<%s>))", issue.DocUrl)
+			}
+
+			b.WriteString("\n")
+		}
+	}
+
+	fmt.Fprintf(&b, "\n#### Ticket contents (%d)\n\n", len(log.TicketContents))
+	if len(log.TicketContents) == 0 {
+		b.WriteString("None\n")
+	} else {
+		b.WriteString("| Path | Architecture | Digest | CDHash |\n|---|---|---|---|\n")
+	}
+
+	for _, ticket := range sortedTickets(log.TicketContents) {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", markdownCode(ticket.Path), markdownEscaper.Replace(orDefault(ticket.Arch, "-")), ticket.DigestAlgorithm, markdownCode(ticket.CDHash))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCode formats value as an inline code span, pipes
+// are still escaped so that the span can be used in a table.
+func markdownCode(value string) string {
+	if len(value) == 0 {
+		return ""
+	}
+
+	fence := "`"
+	for strings.Contains(value, fence) {
+		fence += "`"
+	}
+
+	return fence + strings.ReplaceAll(value, "|", `\|`) + fence
+}
@@ -0,0 +1,138 @@
+// Package logrender renders notarization logs returned
+// by the Notary API in a human-readable form, as plain
+// text for a terminal, Markdown for pull request
+// comments, or as an HTML document.
+package logrender
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+)
+
+// Format selects how a notarization log is rendered.
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatText     Format = "text"
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+)
+
+// ParseFormat returns the Format named by
+// value, "md" is accepted for Markdown.
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(value)); format {
+	case FormatJSON, FormatText, FormatMarkdown, FormatHTML:
+		return format, nil
+
+	case "md":
+		return FormatMarkdown, nil
+
+	default:
+		return "", fmt.Errorf("unsupported notarization log format '%s', must be json, text, markdown, or html", value)
+	}
+}
+
+// Extension returns the suffix appended to
+// the name of a log file saved in the format.
+func (format Format) Extension() string {
+	switch format {
+	case FormatText:
+		return ".txt"
+
+	case FormatMarkdown:
+		return ".md"
+
+	case FormatHTML:
+		return ".html"
+
+	default:
+		return ""
+	}
+}
+
+// Render writes log to w in the specified format.
+func Render(w io.Writer, log *api.NotarizationLog, format Format) error {
+	switch format {
+	case FormatJSON:
+		return json.NewEncoder(w).Encode(log)
+
+	case FormatText:
+		return renderText(w, log)
+
+	case FormatMarkdown:
+		return renderMarkdown(w, log)
+
+	case FormatHTML:
+		return renderHTML(w, log)
+
+	default:
+		return fmt.Errorf("unsupported notarization log format '%s'", format)
+	}
+}
+
+// issueGroup is the issues reported
+// for a single path and architecture.
+type issueGroup struct {
+	Path         string
+	Architecture string
+	Issues       []api.NotarizationIssue
+}
+
+// groupIssues groups issues by their path and
+// architecture, sorted by path then architecture
+// with issues kept in the order they were reported.
+func groupIssues(issues []api.NotarizationIssue) []issueGroup {
+	var groups []issueGroup
+	for _, issue := range issues {
+		i := slices.IndexFunc(groups, func(group issueGroup) bool {
+			return group.Path == issue.Path && group.Architecture == issue.Architecture
+		})
+
+		if i < 0 {
+			groups = append(groups, issueGroup{Path: issue.Path, Architecture: issue.Architecture})
+			i = len(groups) - 1
+		}
+
+		groups[i].Issues = append(groups[i].Issues, issue)
+	}
+
+	slices.SortStableFunc(groups, func(a, b issueGroup) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Architecture, b.Architecture)
+	})
+
+	return groups
+}
+
+// sortedTickets returns the ticket
+// contents sorted by path.
+func sortedTickets(tickets []api.NotarizationTicket) []api.NotarizationTicket {
+	sorted := slices.Clone(tickets)
+	slices.SortStableFunc(sorted, func(a, b api.NotarizationTicket) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Arch, b.Arch)
+	})
+
+	return sorted
+}
+
+func orDefault(value, fallback string) string {
+	if len(value) == 0 {
+		return fallback
+	}
+
+	return value
+}
@@ -0,0 +1,48 @@
+package logrender
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+)
+
+func renderText(w io.Writer, log *api.NotarizationLog) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Notarization log for %s\n\n", orDefault(log.ArchiveFilename, "unknown archive"))
+	fmt.Fprintf(tw, "  Submission:\t%s\n", log.JobID)
+	fmt.Fprintf(tw, "  Status:\t%s (%s)\n", log.Status, log.StatusSummary)
+	fmt.Fprintf(tw, "  Uploaded:\t%s\n", log.UploadDate)
+	fmt.Fprintf(tw, "  SHA-256:\t%s\n", log.SHA256)
+
+	groups := groupIssues(log.Issues)
+	fmt.Fprintf(tw, "\nIssues (%d)\n", len(log.Issues))
+	if len(groups) == 0 {
+		fmt.Fprintln(tw, "  None")
+	}
+
+	for _, group := range groups {
+		fmt.Fprintf(tw, "\n  %s [%s]\n", orDefault(group.Path, "(no path)"), orDefault(group.Architecture, "all architectures"))
+		for _, issue := range group.Issues {
+			fmt.Fprintf(tw, "    %s\t%s\t%s\n", issue.Severity, orDefault(issue.Code, "-"), issue.Message)
+			if len(issue.DocUrl) > 0 {
+				fmt.Fprintf(tw, "    \t\tSee %s\n", issue.DocUrl)
+			}
+		}
+	}
+
+	fmt.Fprintf(tw, "\nTicket contents (%d)\n", len(log.TicketContents))
+	if len(log.TicketContents) == 0 {
+		fmt.Fprintln(tw, "  None")
+	} else {
+		fmt.Fprintln(tw, "  PATH\tARCH\tDIGEST\tCDHASH")
+	}
+
+	for _, ticket := range sortedTickets(log.TicketContents) {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", ticket.Path, orDefault(ticket.Arch, "-"), ticket.DigestAlgorithm, ticket.CDHash)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,178 @@
+// Package policy decides if the issues reported in a
+// notarization log should fail the notarization of a
+// package, even when the Notary accepted it.
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/internal/glob"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+)
+
+// Policy is a set of rules evaluated against
+// each issue in a notarization log, an issue
+// violates the policy if it matches any of the
+// fail rules and isn't allowed by an unexpired
+// Allowance.
+type Policy struct {
+	// FailOnSeverity lists the issue severities,
+	// such as "error", that fail the package.
+	FailOnSeverity []string
+
+	// FailOnCodes lists the issue
+	// codes that fail the package.
+	FailOnCodes []string
+
+	// FailOnPaths lists globs, matched against the
+	// path of the issue, that fail the package. A
+	// "**" element matches any number of path
+	// elements.
+	FailOnPaths []string
+
+	// Allow lists known issues that
+	// don't fail the package.
+	Allow []Allowance
+}
+
+// Allowance permits issues matching all of its
+// non-empty criteria until it expires.
+type Allowance struct {
+	Code    string
+	Path    string
+	Message string
+
+	// Expires is when the allowance stops
+	// applying, the zero value never expires.
+	Expires time.Time
+
+	// Reason documents why the
+	// issue is allowed.
+	Reason string
+}
+
+func (allow *Allowance) matches(issue *api.NotarizationIssue) bool {
+	if len(allow.Code) > 0 && allow.Code != issue.Code {
+		return false
+	}
+
+	if len(allow.Path) > 0 && !glob.Match(allow.Path, issue.Path) {
+		return false
+	}
+
+	if len(allow.Message) > 0 && !strings.Contains(issue.Message, allow.Message) {
+		return false
+	}
+
+	return true
+}
+
+func (allow *Allowance) expired(now time.Time) bool {
+	return !allow.Expires.IsZero() && now.After(allow.Expires)
+}
+
+// Violation is an issue that failed the
+// policy and the rule that it triggered.
+type Violation struct {
+	Issue api.NotarizationIssue
+	Rule  string
+
+	// ExpiredAllowance is set when the issue would
+	// have been allowed if the allowance hadn't
+	// expired.
+	ExpiredAllowance *Allowance
+}
+
+func (violation *Violation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s issue", violation.Issue.Severity)
+	if len(violation.Issue.Code) > 0 {
+		fmt.Fprintf(&b, " %s", violation.Issue.Code)
+	}
+
+	fmt.Fprintf(&b, " at '%s' matched %s", violation.Issue.Path, violation.Rule)
+	if violation.ExpiredAllowance != nil {
+		fmt.Fprintf(&b, " (allowance expired %s)", violation.ExpiredAllowance.Expires.Format(time.DateOnly))
+	}
+
+	return b.String()
+}
+
+// Evaluate returns the issues that violate the
+// policy, allowances are checked against now.
+// It is safe to call on a nil Policy, which
+// never fails a package.
+func (policy *Policy) Evaluate(issues []api.NotarizationIssue, now time.Time) []Violation {
+	if policy == nil {
+		return nil
+	}
+
+	var violations []Violation
+	for i := range issues {
+		issue := &issues[i]
+
+		rule := policy.failRule(issue)
+		if len(rule) == 0 {
+			continue
+		}
+
+		violation := Violation{Issue: *issue, Rule: rule}
+		if allowed, expired := policy.allowance(issue, now); allowed {
+			continue
+		} else if expired != nil {
+			violation.ExpiredAllowance = expired
+		}
+
+		violations = append(violations, violation)
+	}
+
+	return violations
+}
+
+// failRule returns a description of the first
+// fail rule matched by issue, or an empty string
+// if none match.
+func (policy *Policy) failRule(issue *api.NotarizationIssue) string {
+	for _, severity := range policy.FailOnSeverity {
+		if strings.EqualFold(severity, issue.Severity) {
+			return fmt.Sprintf("fail_on_severity '%s'", severity)
+		}
+	}
+
+	for _, code := range policy.FailOnCodes {
+		if code == issue.Code {
+			return fmt.Sprintf("fail_on_codes '%s'", code)
+		}
+	}
+
+	for _, pattern := range policy.FailOnPaths {
+		if glob.Match(pattern, issue.Path) {
+			return fmt.Sprintf("fail_on_paths '%s'", pattern)
+		}
+	}
+
+	return ""
+}
+
+// allowance reports if issue is allowed by an unexpired
+// allowance, otherwise it returns the first expired
+// allowance that matched, if any.
+func (policy *Policy) allowance(issue *api.NotarizationIssue, now time.Time) (bool, *Allowance) {
+	var expired *Allowance
+	for i := range policy.Allow {
+		allow := &policy.Allow[i]
+		if !allow.matches(issue) {
+			continue
+		}
+
+		if !allow.expired(now) {
+			return true, nil
+		} else if expired == nil {
+			expired = allow
+		}
+	}
+
+	return false, expired
+}
@@ -0,0 +1,187 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	unsigned := api.NotarizationIssue{
+		Severity: "error",
+		Code:     "-1",
+		Path:     "Example.zip/Example.app/Contents/MacOS/example",
+		Message:  "The binary is not signed with a valid Developer ID certificate.",
+	}
+
+	sdk := api.NotarizationIssue{
+		Severity: "warning",
+		Code:     "-2",
+		Path:     "Example.zip/Example.app/Contents/Frameworks/Legacy.framework/Legacy",
+		Message:  "The binary uses an SDK older than the 10.9 SDK.",
+	}
+
+	plugin := api.NotarizationIssue{
+		Severity: "warning",
+		Code:     "-3",
+		Path:     "Example.zip/Example.app/Contents/PlugIns/Example.plugin/Contents/MacOS/Example",
+		Message:  "The executable does not have the hardened runtime enabled.",
+	}
+
+	issues := []api.NotarizationIssue{unsigned, sdk, plugin}
+
+	tests := []struct {
+		name   string
+		policy *Policy
+
+		// expected lists the rule each issue failing
+		// the policy matched, by the code of the issue
+		expected map[string]string
+	}{
+		{
+			name:     "nil policy",
+			policy:   nil,
+			expected: map[string]string{},
+		},
+		{
+			name:     "severity",
+			policy:   &Policy{FailOnSeverity: []string{"Error"}},
+			expected: map[string]string{"-1": "fail_on_severity 'Error'"},
+		},
+		{
+			name:     "code",
+			policy:   &Policy{FailOnCodes: []string{"-2"}},
+			expected: map[string]string{"-2": "fail_on_codes '-2'"},
+		},
+		{
+			name:     "path",
+			policy:   &Policy{FailOnPaths: []string{"**/PlugIns/**"}},
+			expected: map[string]string{"-3": "fail_on_paths '**/PlugIns/**'"},
+		},
+		{
+			name: "first matching rule",
+			policy: &Policy{
+				FailOnSeverity: []string{"warning"},
+				FailOnCodes:    []string{"-2"},
+			},
+			expected: map[string]string{"-2": "fail_on_severity 'warning'", "-3": "fail_on_severity 'warning'"},
+		},
+		{
+			name: "allowed by code",
+			policy: &Policy{
+				FailOnSeverity: []string{"error", "warning"},
+				Allow:          []Allowance{{Code: "-2"}},
+			},
+			expected: map[string]string{"-1": "fail_on_severity 'error'", "-3": "fail_on_severity 'warning'"},
+		},
+		{
+			name: "allowed by path",
+			policy: &Policy{
+				FailOnSeverity: []string{"warning"},
+				Allow:          []Allowance{{Path: "**/Frameworks/Legacy.framework/**"}},
+			},
+			expected: map[string]string{"-3": "fail_on_severity 'warning'"},
+		},
+		{
+			name: "allowed by message",
+			policy: &Policy{
+				FailOnSeverity: []string{"warning"},
+				Allow:          []Allowance{{Message: "hardened runtime"}},
+			},
+			expected: map[string]string{"-2": "fail_on_severity 'warning'"},
+		},
+		{
+			name: "allowance must match all criteria",
+			policy: &Policy{
+				FailOnSeverity: []string{"warning"},
+				Allow:          []Allowance{{Code: "-2", Message: "hardened runtime"}},
+			},
+			expected: map[string]string{"-2": "fail_on_severity 'warning'", "-3": "fail_on_severity 'warning'"},
+		},
+		{
+			name: "allowance not yet expired",
+			policy: &Policy{
+				FailOnCodes: []string{"-1"},
+				Allow:       []Allowance{{Code: "-1", Expires: now.Add(time.Hour)}},
+			},
+			expected: map[string]string{},
+		},
+		{
+			name: "allowance expired",
+			policy: &Policy{
+				FailOnCodes: []string{"-1"},
+				Allow:       []Allowance{{Code: "-1", Expires: now.Add(-time.Hour)}},
+			},
+			expected: map[string]string{"-1": "fail_on_codes '-1'"},
+		},
+		{
+			name: "expired allowance with an unexpired one",
+			policy: &Policy{
+				FailOnCodes: []string{"-1"},
+				Allow: []Allowance{
+					{Code: "-1", Expires: now.Add(-time.Hour)},
+					{Path: "Example.zip/**"},
+				},
+			},
+			expected: map[string]string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			violations := test.policy.Evaluate(issues, now)
+
+			failed := make(map[string]string)
+			for _, violation := range violations {
+				failed[violation.Issue.Code] = violation.Rule
+			}
+
+			if len(failed) != len(violations) {
+				t.Errorf("an issue was reported more than once: %+v", violations)
+			}
+
+			for code, rule := range test.expected {
+				if failed[code] != rule {
+					t.Errorf("issue %s failed with rule %q, expected %q", code, failed[code], rule)
+				}
+			}
+
+			for code, rule := range failed {
+				if _, found := test.expected[code]; !found {
+					t.Errorf("issue %s unexpectedly failed with rule %q", code, rule)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateExpiredAllowance(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	issue := api.NotarizationIssue{Severity: "warning", Code: "-2", Path: "Example.pkg/example"}
+
+	policy := &Policy{
+		FailOnSeverity: []string{"warning"},
+		Allow:          []Allowance{{Code: "-2", Expires: time.Date(2026, 5, 31, 23, 59, 59, 0, time.UTC)}},
+	}
+
+	violations := policy.Evaluate([]api.NotarizationIssue{issue}, now)
+	if len(violations) != 1 {
+		t.Fatalf("expected the issue to fail once its allowance expired, got %+v", violations)
+	} else if violations[0].ExpiredAllowance != &policy.Allow[0] {
+		t.Error("expected the violation to record the expired allowance")
+	}
+
+	expected := "warning issue -2 at 'Example.pkg/example' matched fail_on_severity 'warning' (allowance expired 2026-05-31)"
+	if str := violations[0].String(); str != expected {
+		t.Errorf("String() = %q, expected %q", str, expected)
+	}
+
+	// The allowance still applies up to the
+	// moment that it expires
+	if violations = policy.Evaluate([]api.NotarizationIssue{issue}, policy.Allow[0].Expires); len(violations) != 0 {
+		t.Errorf("expected the issue to be allowed until the allowance expires, got %+v", violations)
+	}
+}
@@ -0,0 +1,202 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	barRedrawInterval = 200 * time.Millisecond
+	barWidth          = 30
+)
+
+type barReporter struct {
+	out io.Writer
+
+	lock   sync.Mutex
+	order  []string
+	events map[string]Event
+	drawn  int
+	dirty  bool
+	closed bool
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewBar creates a Reporter that renders a line
+// per package to out, redrawing the lines in
+// place using ANSI escape sequences, and so
+// should only be used when out is a terminal.
+func NewBar(out io.Writer) Reporter {
+	reporter := &barReporter{
+		out:    out,
+		events: make(map[string]Event),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go reporter.redrawLoop()
+	return reporter
+}
+
+func (reporter *barReporter) Report(event Event) {
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+
+	if _, known := reporter.events[event.File]; !known {
+		reporter.order = append(reporter.order, event.File)
+	}
+
+	reporter.events[event.File] = event
+	reporter.dirty = true
+}
+
+func (reporter *barReporter) Close() error {
+	reporter.closeOnce.Do(func() {
+		close(reporter.stop)
+		<-reporter.done
+
+		reporter.lock.Lock()
+		defer reporter.lock.Unlock()
+
+		reporter.draw()
+		reporter.closed = true
+	})
+
+	return nil
+}
+
+// barLogWriter passes writes through to out, first
+// erasing the lines drawn by the reporter and then
+// redrawing them below what was written.
+type barLogWriter struct {
+	reporter *barReporter
+	out      io.Writer
+}
+
+func (writer barLogWriter) Write(p []byte) (int, error) {
+	reporter := writer.reporter
+
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+
+	if reporter.closed {
+		// The final state of the bars
+		// is left on the terminal
+		return writer.out.Write(p)
+	}
+
+	if reporter.drawn > 0 {
+		// Move the cursor back to the first line
+		// drawn and clear to the end of the screen
+		_, _ = fmt.Fprintf(reporter.out, "\x1b[%dA\x1b[J", reporter.drawn)
+		reporter.drawn = 0
+	}
+
+	n, err := writer.out.Write(p)
+
+	reporter.dirty = len(reporter.order) > 0
+	reporter.draw()
+
+	return n, err
+}
+
+func (reporter *barReporter) redrawLoop() {
+	defer close(reporter.done)
+
+	ticker := time.NewTicker(barRedrawInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-reporter.stop:
+			return
+
+		case <-ticker.C:
+			reporter.lock.Lock()
+			reporter.draw()
+			reporter.lock.Unlock()
+		}
+	}
+}
+
+// draw must be called with reporter.lock held.
+func (reporter *barReporter) draw() {
+	if !reporter.dirty {
+		return
+	}
+
+	var buf strings.Builder
+	if reporter.drawn > 0 {
+		// Move the cursor back to the first
+		// line drawn by the previous redraw
+		fmt.Fprintf(&buf, "\x1b[%dA", reporter.drawn)
+	}
+
+	nameWidth := 0
+	for _, file := range reporter.order {
+		nameWidth = max(nameWidth, len(filepath.Base(file)))
+	}
+
+	for _, file := range reporter.order {
+		buf.WriteString("\x1b[2K")
+		buf.WriteString(formatBarLine(reporter.events[file], nameWidth))
+		buf.WriteByte('\n')
+	}
+
+	_, _ = io.WriteString(reporter.out, buf.String())
+	reporter.drawn = len(reporter.order)
+	reporter.dirty = false
+}
+
+func formatBarLine(event Event, nameWidth int) string {
+	name := fmt.Sprintf("%-*s", nameWidth, filepath.Base(event.File))
+
+	switch event.Phase {
+	case PhaseUploading:
+		fraction := 0.0
+		if event.BytesTotal > 0 {
+			fraction = float64(event.BytesUploaded) / float64(event.BytesTotal)
+		}
+
+		filled := int(fraction * barWidth)
+		bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+
+		return fmt.Sprintf("%s [%s] %3.0f%% %s/%s %s/s ETA %s",
+			name, bar, fraction*100,
+			formatBytes(float64(event.BytesUploaded)), formatBytes(float64(event.BytesTotal)),
+			formatBytes(event.BytesPerSecond), formatDuration(event.ETASeconds),
+		)
+
+	case PhaseWaiting:
+		return fmt.Sprintf("%s waiting for notary, poll %d, status %s", name, event.PollCount, event.Status)
+
+	case PhaseFailed:
+		return fmt.Sprintf("%s failed: %s", name, event.Error)
+
+	default:
+		return fmt.Sprintf("%s %s", name, event.Phase)
+	}
+}
+
+func formatBytes(size float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+
+	unit := 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+
+	return fmt.Sprintf("%.1f %s", size, units[unit])
+}
+
+func formatDuration(seconds float64) string {
+	return (time.Duration(seconds) * time.Second).Round(time.Second).String()
+}
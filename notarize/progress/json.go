@@ -0,0 +1,29 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+type jsonReporter struct {
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSON creates a Reporter that writes each
+// event to out as a line of JSON.
+func NewJSON(out io.Writer) Reporter {
+	return &jsonReporter{encoder: json.NewEncoder(out)}
+}
+
+func (reporter *jsonReporter) Report(event Event) {
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+
+	_ = reporter.encoder.Encode(event)
+}
+
+func (reporter *jsonReporter) Close() error {
+	return nil
+}
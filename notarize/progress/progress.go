@@ -0,0 +1,122 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Phase identifies the step of notarization
+// a package is currently undergoing.
+type Phase string
+
+const (
+	PhasePreparing Phase = "preparing"
+	PhaseUploading Phase = "uploading"
+	PhaseWaiting   Phase = "waiting"
+	PhaseLog       Phase = "log"
+	PhaseStapling  Phase = "stapling"
+	PhaseDone      Phase = "done"
+	PhaseFailed    Phase = "failed"
+)
+
+// Event describes the state of a single package
+// at a point in time, it is produced by a Tracker
+// each time the progress of the package changes.
+type Event struct {
+	Time           time.Time `json:"time"`
+	File           string    `json:"file"`
+	Phase          Phase     `json:"phase"`
+	BytesUploaded  int64     `json:"bytesUploaded,omitempty"`
+	BytesTotal     int64     `json:"bytesTotal,omitempty"`
+	PartsUploaded  int       `json:"partsUploaded,omitempty"`
+	BytesPerSecond float64   `json:"bytesPerSecond,omitempty"`
+	ETASeconds     float64   `json:"etaSeconds,omitempty"`
+	PollCount      int       `json:"pollCount,omitempty"`
+	Status         string    `json:"status,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// Reporter defines a destination for progress
+// events produced by one or more Trackers.
+type Reporter interface {
+	// Report records the latest state of a
+	// package, implementations must be safe
+	// for concurrent use.
+	Report(event Event)
+
+	// Close flushes any pending output and
+	// releases resources held by the Reporter.
+	Close() error
+}
+
+// Mode selects how progress is rendered.
+type Mode string
+
+const (
+	ModeAuto Mode = "auto"
+	ModeBar  Mode = "bar"
+	ModeJSON Mode = "json"
+	ModeNone Mode = "none"
+)
+
+// New creates a Reporter writing to the supplied
+// file using the requested mode, ModeAuto renders
+// progress bars when the file is a terminal and
+// newline-delimited JSON events otherwise.
+func New(mode Mode, out *os.File) (Reporter, error) {
+	switch Mode(strings.ToLower(string(mode))) {
+	case ModeAuto:
+		if isTerminal(out) {
+			return NewBar(out), nil
+		}
+
+		return NewJSON(out), nil
+
+	case ModeBar:
+		return NewBar(out), nil
+
+	case ModeJSON:
+		return NewJSON(out), nil
+
+	case ModeNone, "":
+		return Discard, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported progress mode '%s'", mode)
+	}
+}
+
+// LogWriter returns a writer for output, such as log
+// messages, that is shown on the same terminal as the
+// progress rendered by reporter. For a Reporter created
+// by NewBar, the bars are erased before each write and
+// redrawn after it so that neither garbles the other,
+// otherwise w is returned as is.
+func LogWriter(reporter Reporter, w io.Writer) io.Writer {
+	if bar, ok := reporter.(*barReporter); ok {
+		return barLogWriter{reporter: bar, out: w}
+	}
+
+	return w
+}
+
+// Discard is a Reporter that drops all events.
+var Discard Reporter = discard{}
+
+type discard struct{}
+
+func (discard) Report(Event) {}
+
+func (discard) Close() error { return nil }
+
+func isTerminal(file *os.File) bool {
+	stat, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice != 0
+}
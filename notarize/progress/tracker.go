@@ -0,0 +1,110 @@
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// Tracker follows the progress of a single
+// package, deriving throughput and ETA from
+// the updates fed to it by a worker and
+// forwarding each change to a Reporter.
+type Tracker struct {
+	reporter Reporter
+
+	lock        sync.Mutex
+	event       Event
+	uploadStart time.Time
+}
+
+// NewTracker creates a Tracker for the specified
+// file that reports to the supplied Reporter, if
+// reporter is nil events are discarded.
+func NewTracker(reporter Reporter, file string) *Tracker {
+	if reporter == nil {
+		reporter = Discard
+	}
+
+	return &Tracker{
+		reporter: reporter,
+		event: Event{
+			File:  file,
+			Phase: PhasePreparing,
+		},
+	}
+}
+
+// SetPhase records that the package has
+// moved on to the specified phase.
+func (tracker *Tracker) SetPhase(phase Phase) {
+	tracker.update(func(event *Event) {
+		event.Phase = phase
+	})
+}
+
+// StartUpload records the start of the upload
+// of total bytes to the Notary S3 bucket.
+func (tracker *Tracker) StartUpload(total int64) {
+	tracker.update(func(event *Event) {
+		tracker.uploadStart = time.Now()
+
+		event.Phase = PhaseUploading
+		event.BytesTotal = total
+		event.BytesUploaded = 0
+		event.PartsUploaded = 0
+	})
+}
+
+// PartUploaded records that a part of
+// size bytes was successfully uploaded.
+func (tracker *Tracker) PartUploaded(size int64) {
+	tracker.update(func(event *Event) {
+		event.BytesUploaded += size
+		event.PartsUploaded++
+
+		elapsed := time.Since(tracker.uploadStart).Seconds()
+		if elapsed <= 0 {
+			return
+		}
+
+		event.BytesPerSecond = float64(event.BytesUploaded) / elapsed
+		if remaining := event.BytesTotal - event.BytesUploaded; remaining > 0 && event.BytesPerSecond > 0 {
+			event.ETASeconds = float64(remaining) / event.BytesPerSecond
+		} else {
+			event.ETASeconds = 0
+		}
+	})
+}
+
+// Polled records that the submission status
+// was checked and the status returned.
+func (tracker *Tracker) Polled(status string) {
+	tracker.update(func(event *Event) {
+		event.Phase = PhaseWaiting
+		event.PollCount++
+		event.Status = status
+	})
+}
+
+// Finish records the end of processing for the
+// package, failed if err is not nil.
+func (tracker *Tracker) Finish(err error) {
+	tracker.update(func(event *Event) {
+		if err != nil {
+			event.Phase = PhaseFailed
+			event.Error = err.Error()
+		} else {
+			event.Phase = PhaseDone
+		}
+	})
+}
+
+func (tracker *Tracker) update(apply func(event *Event)) {
+	tracker.lock.Lock()
+	apply(&tracker.event)
+	tracker.event.Time = time.Now()
+	event := tracker.event
+	tracker.lock.Unlock()
+
+	tracker.reporter.Report(event)
+}
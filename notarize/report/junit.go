@@ -0,0 +1,103 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     float64          `xml:"time,attr"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      float64         `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut *junitText    `xml:"system-out,omitempty"`
+}
+
+// junitText is written as CDATA so that
+// multi-line output stays readable.
+type junitText struct {
+	Body string `xml:",cdata"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",cdata"`
+}
+
+func (report *Report) writeJUnit(w io.Writer) error {
+	suite := junitTestSuite{
+		Name:      "notarization",
+		Tests:     len(report.Packages),
+		Time:      report.Finished.Sub(report.Started).Seconds(),
+		Timestamp: report.Started.Format("2006-01-02T15:04:05"),
+	}
+
+	for _, pkg := range report.Packages {
+		testCase := junitTestCase{
+			Name:      pkg.File,
+			ClassName: "notarization-helper",
+			Time:      pkg.Duration().Seconds(),
+			SystemOut: &junitText{pkg.summary()},
+		}
+
+		if !pkg.Succeeded() {
+			suite.Failures++
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("notarization status: %s", pkg.Status),
+				Type:    pkg.Status,
+				Body:    strings.Join(pkg.Errors, "\n"),
+			}
+		}
+
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	return encoder.Encode(junitTestSuites{
+		Suites:   []junitTestSuite{suite},
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Time:     suite.Time,
+	})
+}
+
+func (pkg *Package) summary() string {
+	var buf strings.Builder
+
+	fmt.Fprintf(&buf, "SHA-256: %s\n", pkg.SHA256)
+	fmt.Fprintf(&buf, "Submission ID: %s\n", pkg.SubmissionID)
+	fmt.Fprintf(&buf, "Issues: %d\n", pkg.IssueCount)
+	fmt.Fprintf(&buf, "Stapled: %t\n", pkg.Stapled)
+
+	if len(pkg.LogPath) > 0 {
+		fmt.Fprintf(&buf, "Log: %s\n", pkg.LogPath)
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,99 @@
+// Package report produces a machine-readable summary of a
+// notarization run, either as JSON or as JUnit XML so that
+// CI systems can display each package as a test case.
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format selects how a Report is encoded.
+type Format string
+
+const (
+	FormatJSON  Format = "json"
+	FormatJUnit Format = "junit"
+)
+
+// FormatForPath returns the format implied by the
+// extension of path, JUnit XML for .xml files and
+// JSON for everything else.
+func FormatForPath(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".xml") {
+		return FormatJUnit
+	}
+
+	return FormatJSON
+}
+
+// Package is the outcome of notarizing a single package.
+type Package struct {
+	File         string             `json:"file"`
+	SHA256       string             `json:"sha256,omitempty"`
+	SubmissionID string             `json:"submissionId,omitempty"`
+	Status       string             `json:"status"`
+	Phases       map[string]float64 `json:"phaseSeconds,omitempty"`
+	IssueCount   int                `json:"issueCount"`
+	Stapled      bool               `json:"stapled"`
+	LogPath      string             `json:"logPath,omitempty"`
+	Errors       []string           `json:"errors,omitempty"`
+}
+
+// Succeeded reports if the package was accepted
+// by the Notary without any errors occurring.
+func (pkg *Package) Succeeded() bool {
+	return pkg.Status == "Accepted" && len(pkg.Errors) == 0
+}
+
+// Duration returns the total time spent
+// across all phases for the package.
+func (pkg *Package) Duration() time.Duration {
+	var total float64
+	for _, seconds := range pkg.Phases {
+		total += seconds
+	}
+
+	return time.Duration(total * float64(time.Second))
+}
+
+// Report is the outcome of a notarization run.
+type Report struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Packages []Package `json:"packages"`
+}
+
+// Write encodes the report to w in the specified format.
+func (report *Report) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatJSON:
+		return report.writeJSON(w)
+
+	case FormatJUnit:
+		return report.writeJUnit(w)
+
+	default:
+		return fmt.Errorf("unsupported report format '%s'", format)
+	}
+}
+
+// WriteFile writes the report to the file at path, the
+// format is chosen by the extension of the file.
+func (report *Report) WriteFile(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open report file: %w", err)
+	}
+	defer file.Close()
+
+	if err = report.Write(file, FormatForPath(path)); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	return file.Close()
+}
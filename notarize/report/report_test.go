@@ -0,0 +1,101 @@
+package report
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares output with the golden file
+// testdata/name, or replaces it when -update is set.
+func checkGolden(t *testing.T, name string, output []byte) {
+	t.Helper()
+
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, output, 0644); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+	}
+
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	} else if !bytes.Equal(output, expected) {
+		t.Errorf("output doesn't match %s, got:\n%s", golden, output)
+	}
+}
+
+func newTestReport() *Report {
+	started := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+
+	return &Report{
+		Started:  started,
+		Finished: started.Add(95 * time.Second),
+		Packages: []Package{
+			{
+				File:         "build/Example.app",
+				SHA256:       "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+				SubmissionID: "2efe2717-52ef-43a5-96dc-0797e4ca1041",
+				Status:       "Accepted",
+				Phases:       map[string]float64{"upload": 12.5, "wait": 60.25, "staple": 0.75},
+				IssueCount:   1,
+				Stapled:      true,
+				LogPath:      "build/Example.app.notarization.json",
+			},
+			{
+				File:         "build/Example Installer.pkg",
+				SHA256:       "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752",
+				SubmissionID: "6f6b5a3c-0f0e-4e0a-9d5e-6a1f27c0b2b4",
+				Status:       "Invalid",
+				Phases:       map[string]float64{"upload": 8, "wait": 14},
+				IssueCount:   2,
+				Errors: []string{
+					"notarization log violates policy: error issue -1 at 'Example Installer.pkg/Payload/<tool>' matched fail_on_severity 'error'",
+					"staple notarization ticket: \"Example & Co\" isn't signed",
+				},
+			},
+		},
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTestReport().Write(&buf, FormatJSON); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+
+	checkGolden(t, "report.json.golden", buf.Bytes())
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTestReport().Write(&buf, FormatJUnit); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+
+	checkGolden(t, "report.xml.golden", buf.Bytes())
+}
+
+func TestFormatForPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected Format
+	}{
+		{"report.json", FormatJSON},
+		{"report.xml", FormatJUnit},
+		{"REPORT.XML", FormatJUnit},
+		{"report", FormatJSON},
+	}
+
+	for _, test := range tests {
+		if format := FormatForPath(test.path); format != test.expected {
+			t.Errorf("FormatForPath(%q) = %q, expected %q", test.path, format, test.expected)
+		}
+	}
+}
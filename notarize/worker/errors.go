@@ -0,0 +1,27 @@
+package worker
+
+import "errors"
+
+var (
+	// ErrSubmissionInvalid is returned by UploadAndWait
+	// when the Notary marks the submission as invalid.
+	ErrSubmissionInvalid = errors.New("submission is invalid")
+
+	// ErrSubmissionRejected is returned by UploadAndWait
+	// when the Notary rejects the submission.
+	ErrSubmissionRejected = errors.New("submission was rejected")
+
+	// ErrPolicyViolation is returned by UploadAndWait
+	// when an issue in the notarization log of an
+	// accepted submission violates the policy.
+	ErrPolicyViolation = errors.New("notarization log violates policy")
+
+	// ErrTransport wraps errors that occur while
+	// communicating with the Notary API, S3, or
+	// CloudKit.
+	ErrTransport = errors.New("transport error")
+
+	// ErrStaple wraps errors that occur while
+	// stapling the ticket to an accepted package.
+	ErrStaple = errors.New("staple failed")
+)
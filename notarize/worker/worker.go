@@ -3,7 +3,7 @@ package worker
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"hash"
 	"io"
@@ -11,9 +11,14 @@ import (
 	"path/filepath"
 	"regexp"
 	"slices"
+	"sync/atomic"
+	"time"
 
 	"github.com/KatelynHaworth/notarization-helper/v2/config"
 	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/logrender"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/policy"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/progress"
 	"github.com/rs/zerolog"
 )
 
@@ -27,36 +32,172 @@ var (
 	}
 )
 
+// Option applies an optional setting to
+// a Worker when it is created.
+type Option func(worker *Worker)
+
+// WithUploadConfig overrides the default part
+// size and concurrency used by the worker when
+// uploading the package to S3.
+func WithUploadConfig(upload *config.ConfigurationV2_Upload) Option {
+	return func(worker *Worker) {
+		if upload == nil {
+			return
+		}
+
+		if upload.PartSize > 0 {
+			worker.uploadPartSize = upload.PartSize
+		}
+
+		if upload.Concurrency > 0 {
+			worker.uploadConcurrency = upload.Concurrency
+		}
+	}
+}
+
+// WithArchiveConfig sets how the worker creates
+// the temporary ZIP archive for packages that
+// need to be archived before submission.
+func WithArchiveConfig(archive *config.ConfigurationV2_Archive) Option {
+	return func(worker *Worker) {
+		if archive == nil {
+			return
+		}
+
+		worker.archiveTempDir = archive.TempDir
+		worker.archiveOutputDir = archive.OutputDir
+		worker.archiveReproducible = archive.Reproducible
+		worker.archiveMode = archive.Mode
+		worker.archiveStream = archive.Stream
+	}
+}
+
+// WithLogOutput sets the path the notarization log
+// is saved to, instead of next to the package.
+func WithLogOutput(path string) Option {
+	return func(worker *Worker) {
+		worker.logOutput = path
+	}
+}
+
+// WithPollInterval overrides how often the worker
+// checks the status of the submission, a random
+// jitter of up to the same interval is added to
+// each check.
+func WithPollInterval(interval time.Duration) Option {
+	return func(worker *Worker) {
+		if interval > 0 {
+			worker.pollInterval = interval
+		}
+	}
+}
+
+// WithPolicy sets the policy evaluated against the
+// issues in the notarization log, a violation fails
+// the package even if the Notary accepted it.
+func WithPolicy(policy *policy.Policy) Option {
+	return func(worker *Worker) {
+		worker.policy = policy
+	}
+}
+
+// WithProgress sets the Reporter the worker
+// sends progress updates for its package to.
+func WithProgress(reporter progress.Reporter) Option {
+	return func(worker *Worker) {
+		worker.reporter = reporter
+	}
+}
+
 type Worker struct {
+	client *api.Client
 	auth   *config.ConfigurationV2_NotaryAuth
 	target config.Package
 	logger zerolog.Logger
 
+	uploadPartSize    int64
+	uploadConcurrency int
+
+	pollInterval time.Duration
+	policy       *policy.Policy
+
+	archiveTempDir      string
+	archiveOutputDir    string
+	archiveReproducible bool
+	archiveMode         config.ArchiveMode
+	archiveStream       bool
+
+	reporter progress.Reporter
+	progress *progress.Tracker
+
+	logOutput string
+
 	zipFile         string
+	keepZipFile     bool
+	streamArchive   bool
 	uploadFileHash  string
+	uploadFileSize  int64
 	submissionId    string
 	notarizationLog *api.NotarizationLog
+
+	phases       []phaseTiming
+	currentPhase atomic.Value
+	finalState   *api.SubmissionStatusState
+	stapled      bool
+	logPath      string
+	errs         []error
 }
 
-func NewWorker(auth *config.ConfigurationV2_NotaryAuth, p config.Package, logger zerolog.Logger) (*Worker, error) {
+func NewWorker(client *api.Client, auth *config.ConfigurationV2_NotaryAuth, p config.Package, logger zerolog.Logger, opts ...Option) (*Worker, error) {
 	worker := &Worker{
+		client: client,
 		auth:   auth,
 		target: p,
-		logger: logger,
+
+		uploadPartSize:    defaultUploadPartSize,
+		uploadConcurrency: defaultUploadConcurrency,
+		pollInterval:      defaultPollInterval,
 	}
 
-	stat, err := os.Stat(worker.target.File)
-	switch {
-	case err != nil && os.IsNotExist(err):
-		return nil, fmt.Errorf("package file doesn't exist: %w", err)
+	worker.logger = logger.Hook(phaseHook{phase: &worker.currentPhase})
 
-	case err != nil:
-		return nil, fmt.Errorf("stat package file: %w", err)
+	for _, opt := range opts {
+		opt(worker)
+	}
+
+	worker.progress = progress.NewTracker(worker.reporter, worker.target.File)
+	worker.enterPhase(progress.PhasePreparing)
+
+	if worker.uploadPartSize < minUploadPartSize || worker.uploadPartSize > maxUploadPartSize {
+		return nil, fmt.Errorf("upload part size must be between %d and %d bytes", minUploadPartSize, maxUploadPartSize)
+	}
+
+	archive, err := needsArchive(worker.target.File, worker.archiveMode)
+	if err != nil {
+		return nil, err
+	} else if archive && worker.archiveStream {
+		if len(worker.archiveOutputDir) > 0 {
+			return nil, errors.New("an archive can't be both streamed and kept in an output directory")
+		}
 
-	case stat.IsDir() || !worker.allowedFileExtension():
-		if err = worker.zipPackageFile(stat.IsDir()); err != nil {
+		// Nothing is written to disk, the ZIP
+		// is created again when it is uploaded
+		if worker.uploadFileHash, worker.uploadFileSize, err = worker.hashPackageArchive(sha256.New()); err != nil {
+			return nil, fmt.Errorf("hash ZIP of package: %w", err)
+		}
+
+		worker.streamArchive = true
+		return worker, nil
+	} else if archive {
+		// The ZIP is hashed as it is written so
+		// there is no need to read it back again
+		// before it is uploaded
+		if worker.uploadFileHash, err = worker.zipPackageFile(sha256.New()); err != nil {
+			_ = worker.Close()
 			return nil, fmt.Errorf("create temporary ZIP for package: %w", err)
 		}
+
+		return worker, nil
 	}
 
 	if worker.uploadFileHash, err = worker.getFileHash(sha256.New()); err != nil {
@@ -66,6 +207,21 @@ func NewWorker(auth *config.ConfigurationV2_NotaryAuth, p config.Package, logger
 	return worker, nil
 }
 
+// Close removes any temporary files created
+// by the worker, it must be called once the
+// worker is no longer needed.
+func (worker *Worker) Close() error {
+	if len(worker.zipFile) == 0 || worker.keepZipFile {
+		return nil
+	}
+
+	if err := os.Remove(worker.zipFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove temporary ZIP: %w", err)
+	}
+
+	return nil
+}
+
 func (worker *Worker) Logger() zerolog.Logger {
 	return worker.logger
 }
@@ -74,22 +230,72 @@ func (worker *Worker) GetNotarizationLog() *api.NotarizationLog {
 	return worker.notarizationLog
 }
 
-func (worker *Worker) SaveNotarizationLog() (string, error) {
-	logFile, err := os.OpenFile(fmt.Sprintf("%s.notarization-log", worker.target.File), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+// SaveNotarizationLog writes the notarization log to the
+// path set by WithLogOutput or otherwise next to the package,
+// as "<file>.notarization-log" followed by the extension of
+// the format, and returns the path written.
+func (worker *Worker) SaveNotarizationLog(format logrender.Format) (string, error) {
+	path := worker.logOutput
+	if len(path) == 0 {
+		path = fmt.Sprintf("%s.notarization-log%s", worker.target.File, format.Extension())
+	}
+
+	logFile, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
 	if err != nil {
 		return "", fmt.Errorf("open log file: %w", err)
 	}
 	defer logFile.Close()
 
-	if err := json.NewEncoder(logFile).Encode(worker.notarizationLog); err != nil {
+	if err := logrender.Render(logFile, worker.notarizationLog, format); err != nil {
 		return "", fmt.Errorf("write log to file: %w", err)
 	}
 
+	worker.logPath = logFile.Name()
 	return logFile.Name(), nil
 }
 
-func (worker *Worker) allowedFileExtension() bool {
-	return slices.Contains(allowedFileExtensions, filepath.Ext(worker.target.File))
+// ValidatePackage checks that file exists and can be
+// submitted with the archive mode, without reading it.
+func ValidatePackage(file string, mode config.ArchiveMode) error {
+	_, err := needsArchive(file, mode)
+	return err
+}
+
+// needsArchive reports if file must be archived
+// to a ZIP before it is submitted.
+func needsArchive(file string, mode config.ArchiveMode) (bool, error) {
+	stat, err := os.Stat(file)
+	allowed := slices.Contains(allowedFileExtensions, filepath.Ext(file))
+
+	switch {
+	case err != nil && os.IsNotExist(err):
+		return false, fmt.Errorf("package file doesn't exist: %w", err)
+
+	case err != nil:
+		return false, fmt.Errorf("stat package file: %w", err)
+
+	case mode == config.ArchiveModeNever && (stat.IsDir() || !allowed):
+		return false, fmt.Errorf("package must be archived before it can be submitted but the archive mode is '%s'", mode)
+
+	case !stat.IsDir() && stat.Size() == 0:
+		// S3 can't complete a multipart upload
+		// without any parts, and an empty
+		// package can't be notarized anyway
+		return false, errors.New("package file is empty")
+
+	default:
+		return stat.IsDir() || !allowed || (mode == config.ArchiveModeAlways && filepath.Ext(file) != ".zip"), nil
+	}
+}
+
+// submissionName returns the name of the file
+// submitted to the Notary for the package.
+func (worker *Worker) submissionName() string {
+	if worker.streamArchive {
+		return fmt.Sprintf("%s.zip", fileNameEscapeRegexp.ReplaceAllString(filepath.Base(worker.target.File), ""))
+	}
+
+	return filepath.Base(worker.getTargetFile())
 }
 
 func (worker *Worker) getTargetFile() string {
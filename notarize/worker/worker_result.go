@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/progress"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/report"
+	"github.com/rs/zerolog"
+)
+
+// phaseHook adds the current phase of
+// the worker to each message it logs.
+type phaseHook struct {
+	phase *atomic.Value
+}
+
+func (hook phaseHook) Run(event *zerolog.Event, _ zerolog.Level, _ string) {
+	if phase, ok := hook.phase.Load().(progress.Phase); ok {
+		event.Str("phase", string(phase))
+	}
+}
+
+type phaseTiming struct {
+	phase progress.Phase
+	start time.Time
+	end   time.Time
+}
+
+// enterPhase ends the timing of the current
+// phase, if any, and starts timing the next.
+func (worker *Worker) enterPhase(phase progress.Phase) {
+	worker.endPhase()
+
+	worker.phases = append(worker.phases, phaseTiming{phase: phase, start: time.Now()})
+	worker.currentPhase.Store(phase)
+	worker.progress.SetPhase(phase)
+}
+
+func (worker *Worker) endPhase() {
+	if n := len(worker.phases); n > 0 && worker.phases[n-1].end.IsZero() {
+		worker.phases[n-1].end = time.Now()
+	}
+}
+
+// logResult logs the outcome of the worker along with
+// the time spent in each phase, so that notarization
+// latency can be tracked from the logs.
+func (worker *Worker) logResult(err error) {
+	if err != nil {
+		worker.currentPhase.Store(progress.PhaseFailed)
+	} else {
+		worker.currentPhase.Store(progress.PhaseDone)
+	}
+
+	result := worker.Result()
+
+	phases := zerolog.Dict()
+	for phase, seconds := range result.Phases {
+		phases.Float64(phase, seconds)
+	}
+
+	event := worker.logger.Info()
+	if !result.Succeeded() {
+		event = worker.logger.Warn()
+	}
+
+	event.Str("status", result.Status).
+		Float64("durationSeconds", result.Duration().Seconds()).
+		Dict("phaseSeconds", phases).
+		Msg("Notarization worker finished")
+}
+
+// recordError records an error that didn't
+// stop the worker but should be reported.
+func (worker *Worker) recordError(err error) {
+	worker.errs = append(worker.errs, err)
+}
+
+// Result summarises the outcome of the worker,
+// it must only be called once UploadAndWait
+// has returned.
+func (worker *Worker) Result() report.Package {
+	result := report.Package{
+		File:         worker.target.File,
+		SHA256:       worker.uploadFileHash,
+		SubmissionID: worker.submissionId,
+		Phases:       make(map[string]float64, len(worker.phases)),
+		Stapled:      worker.stapled,
+		LogPath:      worker.logPath,
+	}
+
+	switch {
+	case worker.finalState != nil:
+		result.Status = worker.finalState.String()
+
+	case len(worker.submissionId) == 0:
+		result.Status = "NotSubmitted"
+
+	default:
+		result.Status = "Error"
+	}
+
+	for _, timing := range worker.phases {
+		end := timing.end
+		if end.IsZero() {
+			end = time.Now()
+		}
+
+		result.Phases[string(timing.phase)] += end.Sub(timing.start).Seconds()
+	}
+
+	if worker.notarizationLog != nil {
+		result.IssueCount = len(worker.notarizationLog.Issues)
+	}
+
+	for _, err := range worker.errs {
+		result.Errors = append(result.Errors, err.Error())
+	}
+
+	return result
+}
@@ -8,6 +8,7 @@ import (
 	"slices"
 
 	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/progress"
 )
 
 type staplerFunc func(ticket api.SignedTicket) error
@@ -24,13 +25,14 @@ func (worker *Worker) stapleTicket(ctx context.Context) error {
 	}
 
 	worker.logger.Info().Msg("Stapling notarization ticket to package")
+	worker.enterPhase(progress.PhaseStapling)
 	ticketContent := worker.findTicketOfBestFit()
 	if ticketContent == nil {
 		return fmt.Errorf("ticket content of best fit not found in notarization log")
 	}
 
 	worker.logger.Debug().Str("recordName", ticketContent.RecordName()).Msg("Downloading ticket")
-	tickets, err := api.GetTickets(ctx, []api.TicketRecord{{RecordName: ticketContent.RecordName()}})
+	tickets, err := worker.client.GetTickets(ctx, []api.TicketRecord{{RecordName: ticketContent.RecordName()}})
 	if err != nil {
 		return fmt.Errorf("get notarization ticket: %w", err)
 	}
@@ -46,6 +48,7 @@ func (worker *Worker) stapleTicket(ctx context.Context) error {
 	}
 
 	worker.logger.Debug().Str("record", ticketContent.RecordName()).Msg("Successfully stapled ticket")
+	worker.stapled = true
 	return nil
 }
 
@@ -83,8 +86,8 @@ func (worker *Worker) findTicketOfBestFit() *api.NotarizationTicket {
 	// only blocker is proper handling of mach-o files.
 
 	expectedPath := filepath.Base(worker.target.File)
-	if len(worker.zipFile) > 0 {
-		expectedPath = fmt.Sprintf("%s/%s", filepath.Base(worker.zipFile), expectedPath)
+	if len(worker.zipFile) > 0 || worker.streamArchive {
+		expectedPath = fmt.Sprintf("%s/%s", worker.submissionName(), expectedPath)
 	}
 
 	i := slices.IndexFunc(worker.notarizationLog.TicketContents, func(ticket api.NotarizationTicket) bool {
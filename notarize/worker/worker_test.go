@@ -0,0 +1,208 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api/notarytest"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/logrender"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/policy"
+)
+
+func TestWorkerAcceptedAndStapled(t *testing.T) {
+	srv := notarytest.NewServer(notarytest.WithDefaultScenario(notarytest.Scenario{Polls: 2}))
+	defer srv.Close()
+
+	bundle := filepath.Join(t.TempDir(), "Example.app")
+	if err := os.MkdirAll(filepath.Join(bundle, "Contents", "MacOS"), 0755); err != nil {
+		t.Fatalf("create bundle: %v", err)
+	} else if err = os.WriteFile(filepath.Join(bundle, "Contents", "MacOS", "example"), []byte("binary"), 0755); err != nil {
+		t.Fatalf("write executable: %v", err)
+	}
+
+	worker := newTestWorker(t, srv, bundle, true)
+	if err := worker.UploadAndWait(context.Background()); err != nil {
+		t.Fatalf("UploadAndWait() returned error: %v", err)
+	}
+
+	sub := srv.Submissions()[0]
+	if sub.Status != string(notarytest.OutcomeAccepted) {
+		t.Errorf("expected submission to be accepted, status is '%s'", sub.Status)
+	} else if sub.Polls < 2 {
+		t.Errorf("expected the status to be polled at least twice, got %d", sub.Polls)
+	}
+
+	ticketContent := worker.findTicketOfBestFit()
+	if ticketContent == nil {
+		t.Fatal("no ticket for the bundle in the notarization log")
+	}
+
+	expected, found := srv.Ticket(ticketContent.RecordName())
+	if !found {
+		t.Fatalf("fake has no ticket for record '%s'", ticketContent.RecordName())
+	}
+
+	stapled, err := os.ReadFile(filepath.Join(bundle, "Contents", "CodeResources"))
+	if err != nil {
+		t.Fatalf("read stapled ticket: %v", err)
+	} else if !bytes.Equal(stapled, expected) {
+		t.Errorf("stapled ticket = %q, expected %q", stapled, expected)
+	}
+
+	if result := worker.Result(); !result.Stapled {
+		t.Error("expected the result to record that the ticket was stapled")
+	}
+}
+
+func TestWorkerRejected(t *testing.T) {
+	issue := api.NotarizationIssue{
+		Severity: "error",
+		Code:     "-1",
+		Path:     "example.pkg/example",
+		Message:  "The binary is not signed.",
+	}
+
+	srv := notarytest.NewServer(notarytest.WithDefaultScenario(notarytest.Scenario{
+		Outcome: notarytest.OutcomeRejected,
+		Issues:  []api.NotarizationIssue{issue},
+	}))
+	defer srv.Close()
+
+	file := writeTestFile(t, "example.pkg", []byte("package contents"))
+	logFile := filepath.Join(t.TempDir(), "notarization-log.json")
+
+	worker := newTestWorker(t, srv, file, true, WithLogOutput(logFile))
+	if err := worker.UploadAndWait(context.Background()); !errors.Is(err, ErrSubmissionRejected) {
+		t.Fatalf("expected ErrSubmissionRejected, got: %v", err)
+	}
+
+	notarizationLog := worker.GetNotarizationLog()
+	if notarizationLog == nil {
+		t.Fatal("notarization log wasn't fetched for the rejected submission")
+	} else if len(notarizationLog.Issues) != 1 || notarizationLog.Issues[0].Message != issue.Message {
+		t.Errorf("expected the issue in the notarization log, got %+v", notarizationLog.Issues)
+	}
+
+	if path, err := worker.SaveNotarizationLog(logrender.FormatJSON); err != nil {
+		t.Fatalf("SaveNotarizationLog() returned error: %v", err)
+	} else if path != logFile {
+		t.Errorf("log saved to '%s', expected '%s'", path, logFile)
+	}
+
+	saved, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("read saved log: %v", err)
+	} else if !bytes.Contains(saved, []byte(issue.Message)) {
+		t.Errorf("saved log doesn't contain the issue: %s", saved)
+	}
+
+	if contents, _ := os.ReadFile(file); !bytes.Equal(contents, []byte("package contents")) {
+		t.Error("ticket was stapled to a rejected package")
+	}
+}
+
+func TestWorkerInvalidHash(t *testing.T) {
+	srv := notarytest.NewServer()
+	defer srv.Close()
+
+	file := writeTestFile(t, "example.pkg", []byte("package contents"))
+	worker := newTestWorker(t, srv, file, false)
+
+	// The package is hashed when the worker is created,
+	// changing it afterwards makes the upload no longer
+	// match the hash given to the Notary
+	if err := os.WriteFile(file, []byte("changed package contents"), 0644); err != nil {
+		t.Fatalf("change package: %v", err)
+	}
+
+	if err := worker.UploadAndWait(context.Background()); !errors.Is(err, ErrSubmissionInvalid) {
+		t.Fatalf("expected ErrSubmissionInvalid, got: %v", err)
+	}
+
+	if sub := srv.Submissions()[0]; sub.Status != string(notarytest.OutcomeInvalid) {
+		t.Errorf("expected submission to be invalid, status is '%s'", sub.Status)
+	}
+}
+
+func TestWorkerRetriesTransientFailure(t *testing.T) {
+	// The first requests made for the submission are to
+	// S3, which fail and must be retried by the worker
+	srv := notarytest.NewServer(notarytest.WithDefaultScenario(notarytest.Scenario{FailRequests: 2}))
+	defer srv.Close()
+
+	contents := []byte("package contents")
+	file := writeTestFile(t, "example.pkg", contents)
+
+	worker := newTestWorker(t, srv, file, false)
+	if err := worker.UploadAndWait(context.Background()); err != nil {
+		t.Fatalf("UploadAndWait() returned error: %v", err)
+	}
+
+	sub := srv.Submissions()[0]
+	if !bytes.Equal(sub.Uploaded, contents) {
+		t.Error("uploaded contents don't match the package")
+	} else if sub.Status != string(notarytest.OutcomeAccepted) {
+		t.Errorf("expected submission to be accepted, status is '%s'", sub.Status)
+	}
+}
+
+func TestWorkerPolicyViolationNotStapled(t *testing.T) {
+	issue := api.NotarizationIssue{
+		Severity: "warning",
+		Code:     "-2",
+		Path:     "Example.app/Contents/MacOS/example",
+		Message:  "The binary uses an SDK older than the 10.9 SDK.",
+	}
+
+	tests := []struct {
+		name    string
+		policy  *policy.Policy
+		stapled bool
+	}{
+		{"violation", &policy.Policy{FailOnSeverity: []string{"warning"}}, false},
+		{"allowed", &policy.Policy{FailOnSeverity: []string{"warning"}, Allow: []policy.Allowance{{Code: "-2"}}}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := notarytest.NewServer(notarytest.WithDefaultScenario(notarytest.Scenario{
+				Issues: []api.NotarizationIssue{issue},
+			}))
+			defer srv.Close()
+
+			bundle := filepath.Join(t.TempDir(), "Example.app")
+			if err := os.MkdirAll(filepath.Join(bundle, "Contents", "MacOS"), 0755); err != nil {
+				t.Fatalf("create bundle: %v", err)
+			} else if err = os.WriteFile(filepath.Join(bundle, "Contents", "MacOS", "example"), []byte("binary"), 0755); err != nil {
+				t.Fatalf("write executable: %v", err)
+			}
+
+			worker := newTestWorker(t, srv, bundle, true, WithPolicy(test.policy))
+			err := worker.UploadAndWait(context.Background())
+
+			switch {
+			case test.stapled && err != nil:
+				t.Fatalf("UploadAndWait() returned error: %v", err)
+
+			case !test.stapled && !errors.Is(err, ErrPolicyViolation):
+				t.Fatalf("expected ErrPolicyViolation, got: %v", err)
+			}
+
+			if sub := srv.Submissions()[0]; sub.Status != string(notarytest.OutcomeAccepted) {
+				t.Errorf("expected submission to be accepted, status is '%s'", sub.Status)
+			}
+
+			_, err = os.Stat(filepath.Join(bundle, "Contents", "CodeResources"))
+			if stapled := err == nil; stapled != test.stapled {
+				t.Errorf("ticket stapled = %v, expected %v", stapled, test.stapled)
+			} else if result := worker.Result(); result.Stapled != test.stapled {
+				t.Errorf("result records stapled = %v, expected %v", result.Stapled, test.stapled)
+			}
+		})
+	}
+}
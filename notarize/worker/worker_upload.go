@@ -3,35 +3,63 @@ package worker
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"os"
-	"path/filepath"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/progress"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
-	submissionStatusPollInterval = 5 * time.Second
-	submissionStatusPollJitter   = 5
+	defaultPollInterval = 5 * time.Second
+
+	defaultUploadPartSize    = 16 * 1024 * 1024 /* 16MiB */
+	defaultUploadConcurrency = 4
+
+	// minUploadPartSize, maxUploadPartSize and
+	// maxUploadParts reflect the limits S3 places
+	// on multipart uploads.
+	minUploadPartSize = 5 * 1024 * 1024        /* 5MiB */
+	maxUploadPartSize = 5 * 1024 * 1024 * 1024 /* 5GiB */
+	maxUploadParts    = 10000
 )
 
 func (worker *Worker) UploadAndWait(ctx context.Context) error {
+	err := worker.uploadAndWait(ctx)
+	if err != nil {
+		worker.recordError(err)
+	}
+
+	worker.endPhase()
+	worker.progress.Finish(err)
+	worker.logResult(err)
+
+	return err
+}
+
+func (worker *Worker) uploadAndWait(ctx context.Context) error {
+	worker.enterPhase(progress.PhaseUploading)
 	worker.logger.Info().Msg("Creating new notary submission")
-	submissionResp, err := api.StartNewSubmission(ctx, worker.auth.AuthenticateApiRequests, &api.SubmissionRequest{
-		Name: filepath.Base(worker.getTargetFile()),
+	submissionResp, err := worker.client.StartNewSubmission(ctx, worker.auth.AuthenticateApiRequests, &api.SubmissionRequest{
+		Name: worker.submissionName(),
 		Hash: worker.uploadFileHash,
 	})
 
 	if err != nil {
 		worker.logger.Error().Err(err).Msg("Failed to create new submission on Notary API")
-		return fmt.Errorf("create new notary submission entry: %w", err)
+		return fmt.Errorf("create new notary submission entry: %w: %w", ErrTransport, err)
 	}
 
 	worker.submissionId = submissionResp.Id
@@ -40,94 +68,99 @@ func (worker *Worker) UploadAndWait(ctx context.Context) error {
 	worker.logger.Info().Msg("Uploading file to notary S3 bucket")
 	if err = worker.uploadFile(ctx, submissionResp); err != nil {
 		worker.logger.Error().Err(err).Msg("Failed to upload file to S3 bucket")
-		return fmt.Errorf("upload file to S3 notary bucket: %w", err)
+		return fmt.Errorf("upload file to S3 notary bucket: %w: %w", ErrTransport, err)
 	}
 
 	worker.logger.Info().Msg("Successfully uploaded, waiting for submission to complete")
-	finalState := worker.waitForCompletion(ctx)
-	if err = ctx.Err(); err != nil {
+	worker.enterPhase(progress.PhaseWaiting)
+	finalState, err := worker.waitForCompletion(ctx)
+	if ctxErr := ctx.Err(); ctxErr != nil {
 		// NOTE: Context was cancelled, not need to
 		//       attempt further actions as they will
 		//       fail immediately
-		return err
+		return ctxErr
+	} else if err != nil {
+		return fmt.Errorf("wait for submission to complete: %w: %w", ErrTransport, err)
 	}
 
 	worker.logger.Info().Msg("Retrieving notarization log for this submission")
+	worker.finalState = &finalState
+	worker.enterPhase(progress.PhaseLog)
 	if err = worker.downloadNotarizationLog(ctx); err != nil {
 		worker.logger.Error().Err(err).Msg("Failed to retrieve notarization log")
-		return fmt.Errorf("retrieve notarization log: %w", err)
+		return fmt.Errorf("retrieve notarization log: %w: %w", ErrTransport, err)
+	}
+
+	if issueCount := len(worker.notarizationLog.Issues); issueCount != 0 {
+		worker.logger.Warn().Int("numIssues", issueCount).Msg("This package has one or more issues detected by the Notary")
 	}
 
-	if finalState == api.SubmissionStatusStateAccepted {
+	policyErr := worker.evaluatePolicy()
+
+	var stapleErr error
+	if finalState == api.SubmissionStatusStateAccepted && policyErr == nil {
 		if err = worker.stapleTicket(ctx); err != nil {
 			worker.logger.Error().Err(err).Msg("Failed to staple notarization ticket to package")
+			stapleErr = fmt.Errorf("staple notarization ticket: %w: %w", ErrStaple, err)
 		}
 	}
 
-	if issueCount := len(worker.notarizationLog.Issues); issueCount != 0 {
-		worker.logger.Warn().Int("numIssues", issueCount).Msg("This package has one or more issues detected by the Notary")
-	}
+	switch finalState {
+	case api.SubmissionStatusStateAccepted:
+		if policyErr != nil {
+			worker.logger.Warn().Msg("Notarization was completed but the package failed the policy")
+			return policyErr
+		}
 
-	if finalState == api.SubmissionStatusStateAccepted {
 		worker.logger.Info().Msg("Notarization was completed successfully")
-	} else {
+		return stapleErr
+
+	case api.SubmissionStatusStateRejected:
 		worker.logger.Warn().Msg("Notarization was unsuccessful")
-	}
+		return ErrSubmissionRejected
 
-	return nil
+	default:
+		worker.logger.Warn().Msg("Notarization was unsuccessful")
+		return ErrSubmissionInvalid
+	}
 }
 
 func (worker *Worker) uploadFile(ctx context.Context, subResp *api.SubmissionResponse) error {
-	srcFile, err := os.OpenFile(worker.getTargetFile(), os.O_RDONLY, 0644)
+	src, size, err := worker.openUploadSource()
 	if err != nil {
-		return fmt.Errorf("open file for upload: %w", err)
+		return err
 	}
-	defer srcFile.Close()
+	defer src.Close()
 
+	endpoints := worker.client.Endpoints()
 	client := s3.New(s3.Options{
+		// Share the transport of the API client so
+		// that proxy and TLS settings also apply
+		// to the upload
+		HTTPClient:    worker.client.HTTPClient(),
 		Credentials:   subResp,
-		Region:        "us-west-2",
-		UseAccelerate: true,
+		Region:        endpoints.S3Region,
+		UseAccelerate: endpoints.S3Accelerate,
+	}, func(opts *s3.Options) {
+		if len(endpoints.S3Endpoint) > 0 {
+			opts.BaseEndpoint = aws.String(endpoints.S3Endpoint)
+			opts.UseAccelerate = false
+			opts.UsePathStyle = true
+		}
 	})
 
 	multiPart, err := client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
-		Bucket: aws.String(subResp.Bucket),
-		Key:    aws.String(subResp.Object),
+		Bucket:            aws.String(subResp.Bucket),
+		Key:               aws.String(subResp.Object),
+		ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
 	})
 	if err != nil {
 		return fmt.Errorf("start S3 multipart upload: %w", err)
 	}
 
-	buffer := make([]byte, 5*1024*1024 /* 5Mib */)
-	var uploadLog types.CompletedMultipartUpload
-	for part := int32(1); err == nil; part++ {
-		var n int
-		n, err = srcFile.Read(buffer)
-		if err != nil {
-			break
-		}
-
-		worker.logger.Debug().Int32("part", part).Int("size", n).Msg("Uploading part of file to S3")
-		var resp *s3.UploadPartOutput
-		resp, err = client.UploadPart(ctx, &s3.UploadPartInput{
-			Bucket:        multiPart.Bucket,
-			Key:           multiPart.Key,
-			PartNumber:    aws.Int32(part),
-			UploadId:      multiPart.UploadId,
-			Body:          bytes.NewReader(buffer[:n]),
-			ContentLength: aws.Int64(int64(n)),
-		})
-
-		if err == nil {
-			uploadLog.Parts = append(uploadLog.Parts, types.CompletedPart{
-				ChecksumCRC64NVME: resp.ChecksumCRC64NVME,
-				ETag:              resp.ETag,
-				PartNumber:        aws.Int32(part),
-			})
-		}
-	}
-
-	if !errors.Is(err, io.EOF) && err != nil {
+	worker.progress.StartUpload(size)
+	parts, err := worker.uploadParts(ctx, client, multiPart, src, worker.partSizeFor(size))
+	if err != nil {
 		_, _ = client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
 			Bucket:   multiPart.Bucket,
 			Key:      multiPart.Key,
@@ -141,7 +174,7 @@ func (worker *Worker) uploadFile(ctx context.Context, subResp *api.SubmissionRes
 		Bucket:          multiPart.Bucket,
 		Key:             multiPart.Key,
 		UploadId:        multiPart.UploadId,
-		MultipartUpload: &uploadLog,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
 	})
 	if err != nil {
 		return fmt.Errorf("finalise S3 upload: %w", err)
@@ -150,53 +183,234 @@ func (worker *Worker) uploadFile(ctx context.Context, subResp *api.SubmissionRes
 	return nil
 }
 
-func (worker *Worker) waitForCompletion(ctx context.Context) api.SubmissionStatusState {
-	ticker := time.NewTicker(submissionStatusPollInterval)
+// openUploadSource opens the file to upload, or the
+// stream of the ZIP if the package is archived without
+// writing the ZIP to disk, returning it with its size.
+func (worker *Worker) openUploadSource() (io.ReadCloser, int64, error) {
+	if worker.streamArchive {
+		return worker.openArchiveStream(sha256.New()), worker.uploadFileSize, nil
+	}
+
+	srcFile, err := os.OpenFile(worker.getTargetFile(), os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, 0, fmt.Errorf("open file for upload: %w", err)
+	}
+
+	stat, err := srcFile.Stat()
+	if err != nil {
+		_ = srcFile.Close()
+		return nil, 0, fmt.Errorf("stat file for upload: %w", err)
+	}
+
+	return srcFile, stat.Size(), nil
+}
+
+// partSizeFor returns the part size to use for
+// an upload of the specified size, growing the
+// configured part size if required so that the
+// upload fits within the S3 limit on parts.
+func (worker *Worker) partSizeFor(size int64) int64 {
+	partSize := worker.uploadPartSize
+	for size > partSize*maxUploadParts && partSize < maxUploadPartSize {
+		partSize = min(partSize*2, maxUploadPartSize)
+	}
+
+	if partSize != worker.uploadPartSize {
+		worker.logger.Debug().Int64("partSize", partSize).Msg("Increased upload part size to stay within S3 part limit")
+	}
+
+	return partSize
+}
+
+// uploadParts reads the source in chunks of partSize
+// and uploads them to S3 using a pool of at most
+// worker.uploadConcurrency concurrent requests,
+// returning the completed parts ordered by part
+// number ready for CompleteMultipartUpload.
+func (worker *Worker) uploadParts(ctx context.Context, client *s3.Client, multiPart *s3.CreateMultipartUploadOutput, src io.Reader, partSize int64) ([]types.CompletedPart, error) {
+	group, gCtx := errgroup.WithContext(ctx)
+	group.SetLimit(worker.uploadConcurrency)
+
+	var (
+		partsLock sync.Mutex
+		parts     []types.CompletedPart
+
+		// Buffers are recycled between parts so
+		// memory use is bounded by the concurrency
+		// limit rather than the size of the file
+		buffers = make(chan []byte, worker.uploadConcurrency+1)
+	)
+
+	var readErr error
+	for part := int32(1); gCtx.Err() == nil; part++ {
+		var buffer []byte
+		select {
+		case buffer = <-buffers:
+		default:
+			buffer = make([]byte, partSize)
+		}
+
+		n, err := io.ReadFull(src, buffer)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			readErr = fmt.Errorf("read part %d: %w", part, err)
+			break
+		}
+
+		group.Go(func() error {
+			defer func() {
+				select {
+				case buffers <- buffer:
+				default:
+				}
+			}()
+
+			completed, err := worker.uploadPart(gCtx, client, multiPart, part, buffer[:n])
+			if err != nil {
+				return fmt.Errorf("upload part %d: %w", part, err)
+			}
+
+			partsLock.Lock()
+			parts = append(parts, *completed)
+			partsLock.Unlock()
+
+			worker.progress.PartUploaded(int64(n))
+
+			return nil
+		})
+
+		if n < len(buffer) {
+			// A short read means the end
+			// of the source has been reached
+			break
+		}
+	}
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	} else if readErr != nil {
+		return nil, readErr
+	} else if err = ctx.Err(); err != nil {
+		return nil, err
+	} else if len(parts) == 0 {
+		return nil, errors.New("source is empty, at least one part is required")
+	}
+
+	slices.SortFunc(parts, func(a, b types.CompletedPart) int {
+		return int(*a.PartNumber - *b.PartNumber)
+	})
+
+	return parts, nil
+}
+
+func (worker *Worker) uploadPart(ctx context.Context, client *s3.Client, multiPart *s3.CreateMultipartUploadOutput, part int32, body []byte) (*types.CompletedPart, error) {
+	checksum := sha256.Sum256(body)
+	encodedChecksum := base64.StdEncoding.EncodeToString(checksum[:])
+
+	worker.logger.Debug().Int32("part", part).Int("size", len(body)).Msg("Uploading part of file to S3")
+	resp, err := client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:            multiPart.Bucket,
+		Key:               multiPart.Key,
+		PartNumber:        aws.Int32(part),
+		UploadId:          multiPart.UploadId,
+		Body:              bytes.NewReader(body),
+		ContentLength:     aws.Int64(int64(len(body))),
+		ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+		ChecksumSHA256:    aws.String(encodedChecksum),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.CompletedPart{
+		ChecksumSHA256: aws.String(encodedChecksum),
+		ETag:           resp.ETag,
+		PartNumber:     aws.Int32(part),
+	}, nil
+}
+
+func (worker *Worker) waitForCompletion(ctx context.Context) (api.SubmissionStatusState, error) {
+	ticker := time.NewTicker(worker.pollInterval)
 	defer ticker.Stop()
 
 	for {
-		status, err := api.GetSubmissionStatus(ctx, worker.auth.AuthenticateApiRequests, worker.submissionId)
+		status, err := worker.client.GetSubmissionStatus(ctx, worker.auth.AuthenticateApiRequests, worker.submissionId)
 		if err != nil {
 			worker.logger.Error().Err(err).Msg("Notary API returned an error, submission failed")
-			return api.SubmissionStatusStateInvalid
+			return api.SubmissionStatusStateInvalid, err
 		}
 
 		state := status.Status
+		worker.progress.Polled(state.String())
+
 		switch state {
 		case api.SubmissionStatusStateInProgress:
 			worker.logger.Debug().Msg("Submission still in progress")
 
 		case api.SubmissionStatusStateInvalid, api.SubmissionStatusStateRejected:
 			worker.logger.Error().Str("state", state.String()).Msg("Submission failed")
-			return state
+			return state, nil
 
 		case api.SubmissionStatusStateAccepted:
 			worker.logger.Info().Msg("Submission was successful")
-			return state
+			return state, nil
 		}
 
 		// Apply jitter to requests to spread out
 		// load produced by checking the status of
 		// multiple submissions at the same time
-		jitter := time.Duration(rand.Intn(submissionStatusPollJitter)) * time.Second
-		ticker.Reset(submissionStatusPollInterval + jitter)
+		jitter := time.Duration(rand.Int63n(int64(worker.pollInterval)))
+		ticker.Reset(worker.pollInterval + jitter)
 
 		select {
 		case <-ctx.Done():
-			return api.SubmissionStatusStateInvalid
+			return api.SubmissionStatusStateInvalid, ctx.Err()
 
 		case <-ticker.C:
 		}
 	}
 }
 
+// evaluatePolicy checks the issues in the notarization log
+// against the policy of the worker, returning an error that
+// summarises the violations if there are any.
+func (worker *Worker) evaluatePolicy() error {
+	violations := worker.policy.Evaluate(worker.notarizationLog.Issues, time.Now())
+	if len(violations) == 0 {
+		return nil
+	}
+
+	for _, violation := range violations {
+		event := worker.logger.Error().
+			Str("severity", violation.Issue.Severity).
+			Str("code", violation.Issue.Code).
+			Str("path", violation.Issue.Path).
+			Str("issueMessage", violation.Issue.Message).
+			Str("rule", violation.Rule)
+
+		if violation.ExpiredAllowance != nil {
+			event = event.Time("allowanceExpired", violation.ExpiredAllowance.Expires)
+		}
+
+		event.Msg("Notarization log issue violates policy")
+	}
+
+	summary := violations[0].String()
+	if len(violations) > 1 {
+		summary = fmt.Sprintf("%s (and %d more)", summary, len(violations)-1)
+	}
+
+	return fmt.Errorf("%w: %s", ErrPolicyViolation, summary)
+}
+
 func (worker *Worker) downloadNotarizationLog(ctx context.Context) error {
-	urlResp, err := api.GetSubmissionLogURL(ctx, worker.auth.AuthenticateApiRequests, worker.submissionId)
+	urlResp, err := worker.client.GetSubmissionLogURL(ctx, worker.auth.AuthenticateApiRequests, worker.submissionId)
 	if err != nil {
 		return fmt.Errorf("get submission log URL: %w", err)
 	}
 
-	worker.notarizationLog, err = api.DownloadNotaryLog(ctx, urlResp.DeveloperLogUrl)
+	worker.notarizationLog, err = worker.client.DownloadNotaryLog(ctx, urlResp.DeveloperLogUrl)
 	if err != nil {
 		return fmt.Errorf("download log file: %w", err)
 	}
@@ -0,0 +1,178 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/config"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api"
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/api/notarytest"
+	"github.com/rs/zerolog"
+)
+
+// newTestWorker creates a worker for file that submits
+// it to srv, authenticating with an app-specific password
+// so that no App Store Connect key is required.
+func newTestWorker(t *testing.T, srv *notarytest.Server, file string, staple bool, opts ...Option) *Worker {
+	t.Helper()
+
+	client, err := srv.NewApiClient(api.WithLogger(zerolog.Nop()))
+	if err != nil {
+		t.Fatalf("create API client: %v", err)
+	}
+
+	auth := &config.ConfigurationV2_NotaryAuth{Username: "user@example.com", Password: "app-specific-password"}
+	auth.SetApiClient(client)
+
+	worker, err := NewWorker(client, auth, config.Package{File: file, Staple: staple}, zerolog.Nop(),
+		append([]Option{WithPollInterval(time.Millisecond)}, opts...)...)
+	if err != nil {
+		t.Fatalf("create worker: %v", err)
+	}
+
+	t.Cleanup(func() { _ = worker.Close() })
+	return worker
+}
+
+func writeTestFile(t *testing.T, name string, contents []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, contents, 0644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+
+	return path
+}
+
+func TestPartSizeFor(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured int64
+		size       int64
+		expected   int64
+	}{
+		{"small file", defaultUploadPartSize, 1024, defaultUploadPartSize},
+		{"exactly at limit", defaultUploadPartSize, defaultUploadPartSize * maxUploadParts, defaultUploadPartSize},
+		{"one byte over limit", defaultUploadPartSize, defaultUploadPartSize*maxUploadParts + 1, defaultUploadPartSize * 2},
+		{"grows repeatedly", minUploadPartSize, minUploadPartSize*maxUploadParts*5 + 1, minUploadPartSize * 8},
+		{"capped at maximum", maxUploadPartSize / 2, maxUploadPartSize * maxUploadParts * 2, maxUploadPartSize},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			worker := &Worker{uploadPartSize: test.configured, logger: zerolog.Nop()}
+			if partSize := worker.partSizeFor(test.size); partSize != test.expected {
+				t.Errorf("partSizeFor(%d) = %d, expected %d", test.size, partSize, test.expected)
+			}
+		})
+	}
+}
+
+func TestUploadPartOrder(t *testing.T) {
+	srv := notarytest.NewServer()
+	defer srv.Close()
+
+	// Three full parts and a short final part, uploaded
+	// concurrently so they can complete in any order
+	contents := make([]byte, minUploadPartSize*3+1234)
+	for i := range contents {
+		contents[i] = byte(i / minUploadPartSize)
+	}
+
+	file := writeTestFile(t, "package.pkg", contents)
+	worker := newTestWorker(t, srv, file, false, WithUploadConfig(&config.ConfigurationV2_Upload{
+		PartSize:    minUploadPartSize,
+		Concurrency: 4,
+	}))
+
+	if err := worker.UploadAndWait(context.Background()); err != nil {
+		t.Fatalf("UploadAndWait() returned error: %v", err)
+	}
+
+	submissions := srv.Submissions()
+	if len(submissions) != 1 {
+		t.Fatalf("expected 1 submission, got %d", len(submissions))
+	}
+
+	if sub := submissions[0]; sub.Parts != 4 {
+		t.Errorf("expected 4 parts, got %d", sub.Parts)
+	} else if !bytes.Equal(sub.Uploaded, contents) {
+		t.Error("assembled upload does not match the package")
+	}
+}
+
+func TestEmptyPackageRejected(t *testing.T) {
+	file := writeTestFile(t, "empty.pkg", nil)
+
+	srv := notarytest.NewServer()
+	defer srv.Close()
+
+	client, err := srv.NewApiClient()
+	if err != nil {
+		t.Fatalf("create API client: %v", err)
+	}
+
+	if _, err = NewWorker(client, new(config.ConfigurationV2_NotaryAuth), config.Package{File: file}, zerolog.Nop()); err == nil {
+		t.Fatal("expected an error for an empty package")
+	}
+}
+
+func TestUploadStreamedArchive(t *testing.T) {
+	srv := notarytest.NewServer()
+	defer srv.Close()
+
+	bundle := filepath.Join(t.TempDir(), "Example.app")
+	if err := os.MkdirAll(filepath.Join(bundle, "Contents"), 0755); err != nil {
+		t.Fatalf("create bundle: %v", err)
+	} else if err = os.WriteFile(filepath.Join(bundle, "Contents", "Info.plist"), []byte("<plist/>"), 0644); err != nil {
+		t.Fatalf("write Info.plist: %v", err)
+	}
+
+	worker := newTestWorker(t, srv, bundle, false, WithArchiveConfig(&config.ConfigurationV2_Archive{Stream: true}))
+	if len(worker.zipFile) > 0 {
+		t.Fatalf("streamed archive was written to '%s'", worker.zipFile)
+	}
+
+	if err := worker.UploadAndWait(context.Background()); err != nil {
+		t.Fatalf("UploadAndWait() returned error: %v", err)
+	}
+
+	sub := srv.Submissions()[0]
+	if sub.Name != "Example.app.zip" {
+		t.Errorf("expected submission name 'Example.app.zip', got '%s'", sub.Name)
+	} else if int64(len(sub.Uploaded)) != worker.uploadFileSize {
+		t.Errorf("expected %d bytes to be uploaded, got %d", worker.uploadFileSize, len(sub.Uploaded))
+	}
+}
+
+func TestUploadStreamedArchiveChanged(t *testing.T) {
+	srv := notarytest.NewServer()
+	defer srv.Close()
+
+	bundle := filepath.Join(t.TempDir(), "Example.app")
+	if err := os.MkdirAll(bundle, 0755); err != nil {
+		t.Fatalf("create bundle: %v", err)
+	}
+
+	worker := newTestWorker(t, srv, bundle, false, WithArchiveConfig(&config.ConfigurationV2_Archive{Stream: true}))
+
+	// Changing the package after it was hashed
+	// must fail the upload rather than submit a
+	// ZIP that doesn't match the hash
+	if err := os.WriteFile(filepath.Join(bundle, "added"), []byte("added"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	if err := worker.UploadAndWait(context.Background()); err == nil {
+		t.Fatal("expected an error when the package changes during upload")
+	}
+
+	if sub := srv.Submissions()[0]; !sub.Aborted {
+		t.Error("expected the multipart upload to be aborted")
+	}
+}
@@ -1,91 +1,181 @@
 package worker
 
 import (
-	"archive/zip"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/notarize/archive"
+)
+
+var (
+	// reproducibleModTime is used as the modification
+	// time of entries in reproducible archives when
+	// SOURCE_DATE_EPOCH isn't set, it is the earliest
+	// time that can be represented in a ZIP.
+	reproducibleModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
 )
 
-func (worker *Worker) zipPackageFile(isDir bool) error {
+func (worker *Worker) zipPackageFile(hasher hash.Hash) (string, error) {
 	escapedFileName := fileNameEscapeRegexp.ReplaceAllString(filepath.Base(worker.target.File), "")
 
-	zipFile, err := os.CreateTemp("", fmt.Sprintf("*-%s.zip", escapedFileName))
-	if err != nil {
-		return fmt.Errorf("open temporary file for zip: %w", err)
+	var (
+		zipFile *os.File
+		err     error
+	)
+
+	if len(worker.archiveOutputDir) > 0 {
+		// The ZIP is never replaced, so that packages with
+		// the same name in different directories, or a ZIP
+		// left by a previous run, fail instead of one
+		// silently overwriting the other
+		path := filepath.Join(worker.archiveOutputDir, fmt.Sprintf("%s.zip", escapedFileName))
+		zipFile, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			return "", fmt.Errorf("ZIP '%s' already exists in the output directory, another package may have the same name: %w", path, err)
+		}
+	} else {
+		zipFile, err = os.CreateTemp(worker.archiveTempDir, fmt.Sprintf("*-%s.zip", escapedFileName))
 	}
 
-	zipWriter := zip.NewWriter(zipFile)
-	defer zipWriter.Close()
-
-	if isDir {
-		err = filepath.Walk(worker.target.File, func(path string, info os.FileInfo, err error) error {
-			if err != nil || info.IsDir() {
-				return err
-			}
+	if err != nil {
+		return "", fmt.Errorf("open file for zip: %w", err)
+	}
+	defer zipFile.Close()
 
-			if err = worker.addFileToZIP(zipWriter, info, path, true); err != nil {
-				return fmt.Errorf("add file to ZIP: %w", err)
-			}
+	// Record the file straight away so that
+	// it is cleaned up by Close even if the
+	// ZIP fails to be created
+	worker.zipFile = zipFile.Name()
 
-			return nil
-		})
-	} else {
-		err = worker.addFileToZIP(zipWriter, nil, worker.target.File, false)
+	if err = worker.writeArchive(io.MultiWriter(zipFile, hasher)); err != nil {
+		return "", err
+	} else if err = zipFile.Close(); err != nil {
+		return "", fmt.Errorf("close file for zip: %w", err)
 	}
 
-	switch {
-	case err != nil:
-		return fmt.Errorf("walk target directory: %w", err)
+	worker.keepZipFile = len(worker.archiveOutputDir) > 0
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
 
-	default:
-		worker.zipFile = zipFile.Name()
-		return nil
+// hashPackageArchive archives the package without
+// writing the ZIP anywhere, returning the SHA-256
+// hash and size of the ZIP, for when the archive
+// is streamed to S3 by openArchiveStream.
+func (worker *Worker) hashPackageArchive(hasher hash.Hash) (string, int64, error) {
+	counter := new(countingWriter)
+	if err := worker.writeArchive(io.MultiWriter(hasher, counter)); err != nil {
+		return "", 0, err
 	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), counter.n, nil
 }
 
-func (worker *Worker) addFileToZIP(writer *zip.Writer, info os.FileInfo, path string, useRel bool) error {
-	if info == nil {
-		var err error
-		if info, err = os.Stat(path); err != nil {
-			return fmt.Errorf("stat source file: %w", err)
+// openArchiveStream archives the package again, returning
+// a reader the ZIP is streamed through as it is written.
+//
+// The Notary needs the hash of the ZIP before it is
+// uploaded, so the ZIP is written twice, once by
+// hashPackageArchive and again here, which is only
+// possible because the same package contents always
+// produce the same ZIP. If the package changes in
+// between, the reader fails once the ZIP is complete
+// rather than the upload finishing with the wrong hash.
+func (worker *Worker) openArchiveStream(hasher hash.Hash) io.ReadCloser {
+	reader, writer := io.Pipe()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		err := worker.writeArchive(io.MultiWriter(writer, hasher))
+		if err == nil && hex.EncodeToString(hasher.Sum(nil)) != worker.uploadFileHash {
+			err = errors.New("package changed while it was being uploaded, the ZIP no longer matches the submitted hash")
 		}
-	}
 
-	fileHeader, _ := zip.FileInfoHeader(info)
-	if useRel {
-		fileHeader.Name, _ = filepath.Rel(filepath.Dir(worker.target.File), path)
-	} else {
-		fileHeader.Name = filepath.Base(path)
-	}
+		_ = writer.CloseWithError(err)
+	}()
 
-	fileWriter, err := writer.CreateHeader(fileHeader)
-	if err != nil {
-		return fmt.Errorf("create ZIP entry for file: %w", err)
-	}
+	return archiveStream{PipeReader: reader, done: done}
+}
 
-	if info.Mode()&os.ModeSymlink != 0 {
-		linkTarget, err := os.Readlink(path)
-		if err != nil {
-			return fmt.Errorf("read symlink target: %w", err)
-		}
+// archiveStream is the reader returned by openArchiveStream,
+// closing it stops the ZIP being written and waits for the
+// files of the package to be closed.
+type archiveStream struct {
+	*io.PipeReader
+	done chan struct{}
+}
 
-		_, err = fileWriter.Write([]byte(filepath.ToSlash(linkTarget)))
-		if err != nil {
-			return fmt.Errorf("write ZIP file entry for symlink target: %w", err)
-		}
-	} else {
-		sourceFile, err := os.OpenFile(path, os.O_RDONLY, 0644)
+func (stream archiveStream) Close() error {
+	err := stream.PipeReader.Close()
+	<-stream.done
+
+	return err
+}
+
+// writeArchive writes the package to w as a
+// ZIP, using the options set for the worker.
+func (worker *Worker) writeArchive(w io.Writer) error {
+	var opts []archive.Option
+	if worker.archiveReproducible {
+		modTime, err := sourceDateEpoch()
 		if err != nil {
-			return fmt.Errorf("open source file for reading: %w", err)
+			return err
 		}
-		defer sourceFile.Close()
 
-		if _, err := io.Copy(fileWriter, sourceFile); err != nil {
-			return fmt.Errorf("write ZIP entry data from file: %w", err)
-		}
+		opts = append(opts, archive.WithReproducible(modTime))
+	}
+
+	zipWriter := archive.NewWriter(w, opts...)
+	if err := zipWriter.AddTree(worker.target.File); err != nil {
+		return fmt.Errorf("add package to ZIP: %w", err)
+	} else if err = zipWriter.Close(); err != nil {
+		return fmt.Errorf("finalise ZIP: %w", err)
 	}
 
 	return nil
 }
+
+// countingWriter discards what is written
+// to it, counting the number of bytes.
+type countingWriter struct {
+	n int64
+}
+
+func (writer *countingWriter) Write(p []byte) (int, error) {
+	writer.n += int64(len(p))
+	return len(p), nil
+}
+
+// sourceDateEpoch returns the time specified by the
+// SOURCE_DATE_EPOCH environment variable, as defined
+// by https://reproducible-builds.org/specs/source-date-epoch/,
+// falling back to reproducibleModTime if it isn't set.
+func sourceDateEpoch() (time.Time, error) {
+	epoch, found := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !found || len(epoch) == 0 {
+		return reproducibleModTime, nil
+	}
+
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse SOURCE_DATE_EPOCH: %w", err)
+	}
+
+	modTime := time.Unix(seconds, 0).UTC()
+	if modTime.Before(reproducibleModTime) {
+		// ZIP timestamps can't represent
+		// times before 1980
+		modTime = reproducibleModTime
+	}
+
+	return modTime, nil
+}
@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KatelynHaworth/notarization-helper/v2/config"
+	"github.com/rs/zerolog"
+)
+
+func TestZipOutputDirCollision(t *testing.T) {
+	outputDir := t.TempDir()
+	archiveConfig := &config.ConfigurationV2_Archive{OutputDir: outputDir}
+
+	// Two packages with the same name in different
+	// directories would be kept as the same ZIP
+	var bundles []string
+	for _, dir := range []string{"first", "second"} {
+		bundle := filepath.Join(t.TempDir(), dir, "Example.app")
+		if err := os.MkdirAll(bundle, 0755); err != nil {
+			t.Fatalf("create bundle: %v", err)
+		}
+
+		bundles = append(bundles, bundle)
+	}
+
+	first, err := NewWorker(nil, nil, config.Package{File: bundles[0]}, zerolog.Nop(), WithArchiveConfig(archiveConfig))
+	if err != nil {
+		t.Fatalf("create first worker: %v", err)
+	}
+	defer first.Close()
+
+	kept := filepath.Join(outputDir, "Example.app.zip")
+	if first.zipFile != kept {
+		t.Fatalf("expected ZIP to be kept as '%s', got '%s'", kept, first.zipFile)
+	}
+
+	contents, err := os.ReadFile(kept)
+	if err != nil {
+		t.Fatalf("read kept ZIP: %v", err)
+	}
+
+	if _, err = NewWorker(nil, nil, config.Package{File: bundles[1]}, zerolog.Nop(), WithArchiveConfig(archiveConfig)); err == nil {
+		t.Fatal("expected an error for a package whose ZIP already exists")
+	}
+
+	if after, err := os.ReadFile(kept); err != nil || string(after) != string(contents) {
+		t.Errorf("ZIP of the first package was changed or removed: %v", err)
+	}
+}